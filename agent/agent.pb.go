@@ -26,8 +26,10 @@ type CPU struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Total     int32 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
-	Available int32 `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	Total       int32 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Available   int32 `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	Allocatable int32 `protobuf:"varint,3,opt,name=allocatable,proto3" json:"allocatable,omitempty"`
+	Committed   int32 `protobuf:"varint,4,opt,name=committed,proto3" json:"committed,omitempty"`
 }
 
 func (x *CPU) Reset() {
@@ -74,13 +76,29 @@ func (x *CPU) GetAvailable() int32 {
 	return 0
 }
 
+func (x *CPU) GetAllocatable() int32 {
+	if x != nil {
+		return x.Allocatable
+	}
+	return 0
+}
+
+func (x *CPU) GetCommitted() int32 {
+	if x != nil {
+		return x.Committed
+	}
+	return 0
+}
+
 type Memory struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Total     uint64 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
-	Available uint64 `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	Total       uint64 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Available   uint64 `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	Allocatable uint64 `protobuf:"varint,3,opt,name=allocatable,proto3" json:"allocatable,omitempty"`
+	Committed   uint64 `protobuf:"varint,4,opt,name=committed,proto3" json:"committed,omitempty"`
 }
 
 func (x *Memory) Reset() {
@@ -127,13 +145,29 @@ func (x *Memory) GetAvailable() uint64 {
 	return 0
 }
 
+func (x *Memory) GetAllocatable() uint64 {
+	if x != nil {
+		return x.Allocatable
+	}
+	return 0
+}
+
+func (x *Memory) GetCommitted() uint64 {
+	if x != nil {
+		return x.Committed
+	}
+	return 0
+}
+
 type Disk struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Total     uint64 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
-	Available uint64 `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	Total       uint64 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Available   uint64 `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	Allocatable uint64 `protobuf:"varint,3,opt,name=allocatable,proto3" json:"allocatable,omitempty"`
+	Committed   uint64 `protobuf:"varint,4,opt,name=committed,proto3" json:"committed,omitempty"`
 }
 
 func (x *Disk) Reset() {
@@ -180,6 +214,20 @@ func (x *Disk) GetAvailable() uint64 {
 	return 0
 }
 
+func (x *Disk) GetAllocatable() uint64 {
+	if x != nil {
+		return x.Allocatable
+	}
+	return 0
+}
+
+func (x *Disk) GetCommitted() uint64 {
+	if x != nil {
+		return x.Committed
+	}
+	return 0
+}
+
 type Resource struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -478,18 +526,30 @@ var file_agent_agent_proto_rawDesc = []byte{
 	0x0a, 0x11, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x70, 0x72,
 	0x6f, 0x74, 0x6f, 0x12, 0x05, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x1a, 0x13, 0x63, 0x6f, 0x6d, 0x6d,
 	0x6f, 0x6e, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
-	0x39, 0x0a, 0x03, 0x43, 0x50, 0x55, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18,
+	0x79, 0x0a, 0x03, 0x43, 0x50, 0x55, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18,
 	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x1c, 0x0a, 0x09,
 	0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x09, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x22, 0x3c, 0x0a, 0x06, 0x4d, 0x65,
+	0x09, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x61, 0x6c,
+	0x6c, 0x6f, 0x63, 0x61, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0b, 0x61, 0x6c, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1c, 0x0a, 0x09,
+	0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x22, 0x7c, 0x0a, 0x06, 0x4d, 0x65,
 	0x6d, 0x6f, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x04, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x61, 0x76,
 	0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x61,
-	0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x22, 0x3a, 0x0a, 0x04, 0x44, 0x69, 0x73, 0x6b,
+	0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x61, 0x6c, 0x6c, 0x6f,
+	0x63, 0x61, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x61,
+	0x6c, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f,
+	0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x63,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x22, 0x7a, 0x0a, 0x04, 0x44, 0x69, 0x73, 0x6b,
 	0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52,
 	0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61,
 	0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x61, 0x76, 0x61, 0x69, 0x6c,
-	0x61, 0x62, 0x6c, 0x65, 0x22, 0x70, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x61, 0x62, 0x6c, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x61, 0x6c, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x61,
+	0x62, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x61, 0x6c, 0x6c, 0x6f, 0x63,
+	0x61, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x74, 0x65, 0x64, 0x22, 0x70, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
 	0x12, 0x1c, 0x0a, 0x03, 0x63, 0x70, 0x75, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e,
 	0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x43, 0x50, 0x55, 0x52, 0x03, 0x63, 0x70, 0x75, 0x12, 0x25,
 	0x0a, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d,
@@ -513,7 +573,7 @@ var file_agent_agent_proto_rawDesc = []byte{
 	0x79, 0x12, 0x2d, 0x0a, 0x09, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x01,
 	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x49, 0x6e, 0x73,
 	0x74, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x09, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73,
-	0x32, 0xf3, 0x01, 0x0a, 0x03, 0x52, 0x70, 0x63, 0x12, 0x43, 0x0a, 0x09, 0x69, 0x6e, 0x73, 0x74,
+	0x32, 0x8b, 0x04, 0x0a, 0x03, 0x52, 0x70, 0x63, 0x12, 0x43, 0x0a, 0x09, 0x69, 0x6e, 0x73, 0x74,
 	0x61, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x1a, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x47, 0x65,
 	0x74, 0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
 	0x74, 0x1a, 0x18, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x47, 0x65, 0x74, 0x49, 0x6e, 0x73,
@@ -528,10 +588,27 @@ var file_agent_agent_proto_rawDesc = []byte{
 	0x0a, 0x06, 0x6c, 0x61, 0x75, 0x6e, 0x63, 0x68, 0x12, 0x15, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f,
 	0x6e, 0x2e, 0x4c, 0x61, 0x75, 0x6e, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
 	0x13, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x4c, 0x61, 0x75, 0x6e, 0x63, 0x68, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x28, 0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x65, 0x72, 0x61, 0x79, 0x61, 0x72, 0x73, 0x6c, 0x61, 0x6e, 0x2f,
-	0x6d, 0x75, 0x6c, 0x74, 0x69, 0x76, 0x65, 0x72, 0x73, 0x65, 0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x30, 0x0a, 0x04, 0x73, 0x74, 0x6f, 0x70, 0x12, 0x13,
+	0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x53, 0x74, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x53, 0x74, 0x6f,
+	0x70, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x07, 0x73, 0x75, 0x73, 0x70,
+	0x65, 0x6e, 0x64, 0x12, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x53, 0x75, 0x73,
+	0x70, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x63, 0x6f,
+	0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x53, 0x75, 0x73, 0x70, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x22, 0x00, 0x12, 0x3b, 0x0a, 0x07, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x12, 0x14,
+	0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x46,
+	0x72, 0x61, 0x6d, 0x65, 0x1a, 0x14, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x46, 0x6f,
+	0x72, 0x77, 0x61, 0x72, 0x64, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01,
+	0x12, 0x3a, 0x0a, 0x08, 0x73, 0x73, 0x68, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x12, 0x16, 0x2e, 0x63,
+	0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x53, 0x53, 0x48, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x53, 0x53,
+	0x48, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x30, 0x0a, 0x04,
+	0x65, 0x78, 0x65, 0x63, 0x12, 0x13, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x45, 0x78,
+	0x65, 0x63, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x63, 0x6f, 0x6d, 0x6d,
+	0x6f, 0x6e, 0x2e, 0x45, 0x78, 0x65, 0x63, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x28,
+	0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x65, 0x72, 0x61,
+	0x79, 0x61, 0x72, 0x73, 0x6c, 0x61, 0x6e, 0x2f, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x76, 0x65, 0x72,
+	0x73, 0x65, 0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -560,9 +637,18 @@ var file_agent_agent_proto_goTypes = []any{
 	(*common.GetInfoRequest)(nil), // 9: common.GetInfoRequest
 	(*common.ShellRequest)(nil),   // 10: common.ShellRequest
 	(*common.LaunchRequest)(nil),  // 11: common.LaunchRequest
-	(*common.GetInfoReply)(nil),   // 12: common.GetInfoReply
-	(*common.ShellReply)(nil),     // 13: common.ShellReply
-	(*common.LaunchReply)(nil),    // 14: common.LaunchReply
+	(*common.StopRequest)(nil),    // 12: common.StopRequest
+	(*common.SuspendRequest)(nil), // 13: common.SuspendRequest
+	(*common.ForwardFrame)(nil),   // 14: common.ForwardFrame
+	(*common.SSHInfoRequest)(nil), // 15: common.SSHInfoRequest
+	(*common.ExecRequest)(nil),    // 16: common.ExecRequest
+	(*common.GetInfoReply)(nil),   // 17: common.GetInfoReply
+	(*common.ShellReply)(nil),     // 18: common.ShellReply
+	(*common.LaunchReply)(nil),    // 19: common.LaunchReply
+	(*common.StopReply)(nil),      // 20: common.StopReply
+	(*common.SuspendReply)(nil),   // 21: common.SuspendReply
+	(*common.SSHInfoReply)(nil),   // 22: common.SSHInfoReply
+	(*common.ExecReply)(nil),      // 23: common.ExecReply
 }
 var file_agent_agent_proto_depIdxs = []int32{
 	0,  // 0: agent.Resource.cpu:type_name -> agent.CPU
@@ -574,12 +660,22 @@ var file_agent_agent_proto_depIdxs = []int32{
 	9,  // 6: agent.Rpc.info:input_type -> common.GetInfoRequest
 	10, // 7: agent.Rpc.shell:input_type -> common.ShellRequest
 	11, // 8: agent.Rpc.launch:input_type -> common.LaunchRequest
-	8,  // 9: agent.Rpc.instances:output_type -> agent.GetInstancesReply
-	12, // 10: agent.Rpc.info:output_type -> common.GetInfoReply
-	13, // 11: agent.Rpc.shell:output_type -> common.ShellReply
-	14, // 12: agent.Rpc.launch:output_type -> common.LaunchReply
-	9,  // [9:13] is the sub-list for method output_type
-	5,  // [5:9] is the sub-list for method input_type
+	12, // 9: agent.Rpc.stop:input_type -> common.StopRequest
+	13, // 10: agent.Rpc.suspend:input_type -> common.SuspendRequest
+	14, // 11: agent.Rpc.forward:input_type -> common.ForwardFrame
+	15, // 12: agent.Rpc.ssh_info:input_type -> common.SSHInfoRequest
+	16, // 13: agent.Rpc.exec:input_type -> common.ExecRequest
+	8,  // 14: agent.Rpc.instances:output_type -> agent.GetInstancesReply
+	17, // 15: agent.Rpc.info:output_type -> common.GetInfoReply
+	18, // 16: agent.Rpc.shell:output_type -> common.ShellReply
+	19, // 17: agent.Rpc.launch:output_type -> common.LaunchReply
+	20, // 18: agent.Rpc.stop:output_type -> common.StopReply
+	21, // 19: agent.Rpc.suspend:output_type -> common.SuspendReply
+	14, // 20: agent.Rpc.forward:output_type -> common.ForwardFrame
+	22, // 21: agent.Rpc.ssh_info:output_type -> common.SSHInfoReply
+	23, // 22: agent.Rpc.exec:output_type -> common.ExecReply
+	14, // [14:23] is the sub-list for method output_type
+	5,  // [5:14] is the sub-list for method input_type
 	5,  // [5:5] is the sub-list for extension type_name
 	5,  // [5:5] is the sub-list for extension extendee
 	0,  // [0:5] is the sub-list for field type_name
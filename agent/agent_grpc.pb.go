@@ -24,6 +24,11 @@ const (
 	Rpc_Info_FullMethodName      = "/agent.Rpc/info"
 	Rpc_Shell_FullMethodName     = "/agent.Rpc/shell"
 	Rpc_Launch_FullMethodName    = "/agent.Rpc/launch"
+	Rpc_Stop_FullMethodName      = "/agent.Rpc/stop"
+	Rpc_Suspend_FullMethodName   = "/agent.Rpc/suspend"
+	Rpc_Forward_FullMethodName   = "/agent.Rpc/forward"
+	Rpc_SshInfo_FullMethodName   = "/agent.Rpc/ssh_info"
+	Rpc_Exec_FullMethodName      = "/agent.Rpc/exec"
 )
 
 // RpcClient is the client API for Rpc service.
@@ -34,6 +39,11 @@ type RpcClient interface {
 	Info(ctx context.Context, in *common.GetInfoRequest, opts ...grpc.CallOption) (*common.GetInfoReply, error)
 	Shell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[common.ShellRequest, common.ShellReply], error)
 	Launch(ctx context.Context, in *common.LaunchRequest, opts ...grpc.CallOption) (*common.LaunchReply, error)
+	Stop(ctx context.Context, in *common.StopRequest, opts ...grpc.CallOption) (*common.StopReply, error)
+	Suspend(ctx context.Context, in *common.SuspendRequest, opts ...grpc.CallOption) (*common.SuspendReply, error)
+	Forward(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame], error)
+	SshInfo(ctx context.Context, in *common.SSHInfoRequest, opts ...grpc.CallOption) (*common.SSHInfoReply, error)
+	Exec(ctx context.Context, in *common.ExecRequest, opts ...grpc.CallOption) (*common.ExecReply, error)
 }
 
 type rpcClient struct {
@@ -87,6 +97,59 @@ func (c *rpcClient) Launch(ctx context.Context, in *common.LaunchRequest, opts .
 	return out, nil
 }
 
+func (c *rpcClient) Stop(ctx context.Context, in *common.StopRequest, opts ...grpc.CallOption) (*common.StopReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(common.StopReply)
+	err := c.cc.Invoke(ctx, Rpc_Stop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Suspend(ctx context.Context, in *common.SuspendRequest, opts ...grpc.CallOption) (*common.SuspendReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(common.SuspendReply)
+	err := c.cc.Invoke(ctx, Rpc_Suspend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Forward(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Rpc_ServiceDesc.Streams[1], Rpc_Forward_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[common.ForwardFrame, common.ForwardFrame]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_ForwardClient = grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame]
+
+func (c *rpcClient) SshInfo(ctx context.Context, in *common.SSHInfoRequest, opts ...grpc.CallOption) (*common.SSHInfoReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(common.SSHInfoReply)
+	err := c.cc.Invoke(ctx, Rpc_SshInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Exec(ctx context.Context, in *common.ExecRequest, opts ...grpc.CallOption) (*common.ExecReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(common.ExecReply)
+	err := c.cc.Invoke(ctx, Rpc_Exec_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RpcServer is the server API for Rpc service.
 // All implementations must embed UnimplementedRpcServer
 // for forward compatibility.
@@ -95,6 +158,11 @@ type RpcServer interface {
 	Info(context.Context, *common.GetInfoRequest) (*common.GetInfoReply, error)
 	Shell(grpc.BidiStreamingServer[common.ShellRequest, common.ShellReply]) error
 	Launch(context.Context, *common.LaunchRequest) (*common.LaunchReply, error)
+	Stop(context.Context, *common.StopRequest) (*common.StopReply, error)
+	Suspend(context.Context, *common.SuspendRequest) (*common.SuspendReply, error)
+	Forward(grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]) error
+	SshInfo(context.Context, *common.SSHInfoRequest) (*common.SSHInfoReply, error)
+	Exec(context.Context, *common.ExecRequest) (*common.ExecReply, error)
 	mustEmbedUnimplementedRpcServer()
 }
 
@@ -117,6 +185,21 @@ func (UnimplementedRpcServer) Shell(grpc.BidiStreamingServer[common.ShellRequest
 func (UnimplementedRpcServer) Launch(context.Context, *common.LaunchRequest) (*common.LaunchReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Launch not implemented")
 }
+func (UnimplementedRpcServer) Stop(context.Context, *common.StopRequest) (*common.StopReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+func (UnimplementedRpcServer) Suspend(context.Context, *common.SuspendRequest) (*common.SuspendReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Suspend not implemented")
+}
+func (UnimplementedRpcServer) Forward(grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]) error {
+	return status.Errorf(codes.Unimplemented, "method Forward not implemented")
+}
+func (UnimplementedRpcServer) SshInfo(context.Context, *common.SSHInfoRequest) (*common.SSHInfoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SshInfo not implemented")
+}
+func (UnimplementedRpcServer) Exec(context.Context, *common.ExecRequest) (*common.ExecReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exec not implemented")
+}
 func (UnimplementedRpcServer) mustEmbedUnimplementedRpcServer() {}
 func (UnimplementedRpcServer) testEmbeddedByValue()             {}
 
@@ -199,6 +282,85 @@ func _Rpc_Launch_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Rpc_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.StopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Stop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Stop(ctx, req.(*common.StopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Suspend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.SuspendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Suspend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Suspend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Suspend(ctx, req.(*common.SuspendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Forward_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RpcServer).Forward(&grpc.GenericServerStream[common.ForwardFrame, common.ForwardFrame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_ForwardServer = grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]
+
+func _Rpc_SshInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.SSHInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).SshInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_SshInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).SshInfo(ctx, req.(*common.SSHInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Exec_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.ExecRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Exec(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Exec_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Exec(ctx, req.(*common.ExecRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Rpc_ServiceDesc is the grpc.ServiceDesc for Rpc service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -218,6 +380,22 @@ var Rpc_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "launch",
 			Handler:    _Rpc_Launch_Handler,
 		},
+		{
+			MethodName: "stop",
+			Handler:    _Rpc_Stop_Handler,
+		},
+		{
+			MethodName: "suspend",
+			Handler:    _Rpc_Suspend_Handler,
+		},
+		{
+			MethodName: "ssh_info",
+			Handler:    _Rpc_SshInfo_Handler,
+		},
+		{
+			MethodName: "exec",
+			Handler:    _Rpc_Exec_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -226,6 +404,12 @@ var Rpc_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "forward",
+			Handler:       _Rpc_Forward_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "agent/agent.proto",
 }
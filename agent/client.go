@@ -20,6 +20,11 @@ type Client interface {
 	Shell(ctx context.Context) (grpc.BidiStreamingClient[common.ShellRequest, common.ShellReply], error)
 	Close() error
 	Launch(ctx context.Context, request *common.LaunchRequest) (*common.LaunchReply, error)
+	Stop(ctx context.Context, request *common.StopRequest) (*common.StopReply, error)
+	Suspend(ctx context.Context, request *common.SuspendRequest) (*common.SuspendReply, error)
+	Forward(ctx context.Context) (grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame], error)
+	SSHInfo(ctx context.Context, request *common.SSHInfoRequest) (*common.SSHInfoReply, error)
+	Exec(ctx context.Context, request *common.ExecRequest) (*common.ExecReply, error)
 }
 
 func (c *client) Close() error {
@@ -38,10 +43,30 @@ func (c *client) Launch(ctx context.Context, launchRequest *common.LaunchRequest
 	return c.client.Launch(ctx, launchRequest)
 }
 
+func (c *client) Stop(ctx context.Context, request *common.StopRequest) (*common.StopReply, error) {
+	return c.client.Stop(ctx, request)
+}
+
+func (c *client) Suspend(ctx context.Context, request *common.SuspendRequest) (*common.SuspendReply, error) {
+	return c.client.Suspend(ctx, request)
+}
+
 func (c *client) Shell(ctx context.Context) (grpc.BidiStreamingClient[common.ShellRequest, common.ShellReply], error) {
 	return c.client.Shell(ctx)
 }
 
+func (c *client) Forward(ctx context.Context) (grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame], error) {
+	return c.client.Forward(ctx)
+}
+
+func (c *client) SSHInfo(ctx context.Context, request *common.SSHInfoRequest) (*common.SSHInfoReply, error) {
+	return c.client.SshInfo(ctx, request)
+}
+
+func (c *client) Exec(ctx context.Context, request *common.ExecRequest) (*common.ExecReply, error) {
+	return c.client.Exec(ctx, request)
+}
+
 func NewClient(addr string) (Client, error) {
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
 	conn, err := grpc.NewClient(addr, opts...)
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	resourceLabels = []string{"type"}
+
+	cpuDesc = prometheus.NewDesc("multiverse_agent_cpu_cores",
+		"Host cpu cores by type.", resourceLabels, nil)
+	memoryDesc = prometheus.NewDesc("multiverse_agent_memory_bytes",
+		"Host memory by type.", resourceLabels, nil)
+	diskDesc = prometheus.NewDesc("multiverse_agent_disk_bytes",
+		"Host disk by type.", resourceLabels, nil)
+	instancesDesc = prometheus.NewDesc("multiverse_agent_instances",
+		"Number of local instances by state.", []string{"state"}, nil)
+)
+
+type collector struct {
+	state State
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func gauge(ch chan<- prometheus.Metric, desc *prometheus.Desc, value float64, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.state.Snapshot()
+
+	if resource := s.Resource; resource != nil {
+		gauge(ch, cpuDesc, float64(resource.Cpu.Total), "total")
+		gauge(ch, cpuDesc, float64(resource.Cpu.Available), "available")
+		gauge(ch, cpuDesc, float64(resource.Cpu.Allocatable), "allocatable")
+		gauge(ch, cpuDesc, float64(resource.Cpu.Committed), "committed")
+		gauge(ch, memoryDesc, float64(resource.Memory.Total), "total")
+		gauge(ch, memoryDesc, float64(resource.Memory.Available), "available")
+		gauge(ch, memoryDesc, float64(resource.Memory.Allocatable), "allocatable")
+		gauge(ch, memoryDesc, float64(resource.Memory.Committed), "committed")
+		gauge(ch, diskDesc, float64(resource.Disk.Total), "total")
+		gauge(ch, diskDesc, float64(resource.Disk.Available), "available")
+		gauge(ch, diskDesc, float64(resource.Disk.Allocatable), "allocatable")
+		gauge(ch, diskDesc, float64(resource.Disk.Committed), "committed")
+	}
+
+	instances := make(map[string]int)
+	for _, instance := range s.Instances {
+		instances[instance.State]++
+	}
+	for state, count := range instances {
+		gauge(ch, instancesDesc, float64(count), state)
+	}
+}
+
+func NewCollector(state State) prometheus.Collector {
+	return &collector{
+		state: state,
+	}
+}
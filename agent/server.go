@@ -3,12 +3,15 @@ package agent
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/erayarslan/multiverse/common"
+	"github.com/erayarslan/multiverse/metrics"
 
 	"github.com/erayarslan/multiverse/multipass"
 
@@ -18,42 +21,55 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+const forwardDialTimeout = 10 * time.Second
+
 type Info struct {
 	Port int
 }
 
 type Server interface {
 	Serve() error
+	ServeTunnel(conn net.Conn) error
+	GracefulStop(timeout time.Duration)
 	Port() int
 }
 
+type shellSession struct {
+	ssh    SSH
+	stderr io.Writer
+}
+
 type server struct {
 	UnimplementedRpcServer
 	multipassClient multipass.Client
 	listener        net.Listener
+	tunnelListener  *tunnelListener
 	state           State
 	grpcServer      *grpc.Server
-	sshMap          map[string]SSH
+	sshMap          map[string]*shellSession
 	sshMu           sync.RWMutex
 }
 
 func (s *server) GetSSH(uid string) SSH {
 	s.sshMu.Lock()
 	defer s.sshMu.Unlock()
-	return s.sshMap[uid]
+	if session, ok := s.sshMap[uid]; ok {
+		return session.ssh
+	}
+	return nil
 }
 
-func (s *server) addSSH(uid string, ssh SSH) {
+func (s *server) addSSH(uid string, session *shellSession) {
 	s.sshMu.Lock()
 	defer s.sshMu.Unlock()
-	s.sshMap[uid] = ssh
+	s.sshMap[uid] = session
 }
 
 func (s *server) removeSSH(uid string) {
 	s.sshMu.Lock()
 	defer s.sshMu.Unlock()
-	if ssh, ok := s.sshMap[uid]; ok {
-		err := ssh.Close()
+	if session, ok := s.sshMap[uid]; ok {
+		err := session.ssh.Close()
 		if err != nil {
 			log.Printf("failed to close ssh: %v", err)
 		}
@@ -61,16 +77,46 @@ func (s *server) removeSSH(uid string) {
 	delete(s.sshMap, uid)
 }
 
+// closeShells tells shell users the worker is shutting down and closes their
+// sessions, which ends the shell rpcs.
+func (s *server) closeShells() {
+	s.sshMu.RLock()
+	defer s.sshMu.RUnlock()
+	for id, session := range s.sshMap {
+		if _, err := session.stderr.Write(common.ShutdownNotice().GetErrBuffer()); err != nil {
+			log.Printf("failed to notify ssh %s: %v", id, err)
+		}
+		if err := session.ssh.Close(); err != nil {
+			log.Printf("failed to close ssh: %v", err)
+		}
+	}
+}
+
 func (s *server) Serve() error {
+	go func() {
+		if err := s.grpcServer.Serve(s.tunnelListener); err != nil {
+			log.Printf("error while serving agent tunnel: %v", err)
+		}
+	}()
 	return s.grpcServer.Serve(s.listener)
 }
 
+// ServeTunnel serves the agent rpc on a connection tunneled through master.
+func (s *server) ServeTunnel(conn net.Conn) error {
+	return s.tunnelListener.serve(conn)
+}
+
+func (s *server) GracefulStop(timeout time.Duration) {
+	s.closeShells()
+	common.GracefulStop(s.grpcServer, timeout)
+}
+
 func (s *server) Port() int {
 	return s.listener.Addr().(*net.TCPAddr).Port
 }
 
 func (s *server) Instances(_ context.Context, _ *GetInstancesRequest) (*GetInstancesReply, error) {
-	multipassInstances := s.state.GetState().Instances
+	multipassInstances := s.state.Snapshot().Instances
 
 	instances := make([]*Instance, len(multipassInstances))
 	for i, multipassInstance := range multipassInstances {
@@ -87,14 +133,82 @@ func (s *server) Instances(_ context.Context, _ *GetInstancesRequest) (*GetInsta
 	}, nil
 }
 
+// lifecycle actions refresh the state so master sees the change at once
 func (s *server) Launch(ctx context.Context, req *common.LaunchRequest) (*common.LaunchReply, error) {
+	defer s.state.Refresh()
 	return s.multipassClient.Launch(ctx, req)
 }
 
+func (s *server) Stop(ctx context.Context, req *common.StopRequest) (*common.StopReply, error) {
+	defer s.state.Refresh()
+	return s.multipassClient.Stop(ctx, req)
+}
+
+func (s *server) Suspend(ctx context.Context, req *common.SuspendRequest) (*common.SuspendReply, error) {
+	defer s.state.Refresh()
+	return s.multipassClient.Suspend(ctx, req)
+}
+
 func (s *server) Info(ctx context.Context, req *common.GetInfoRequest) (*common.GetInfoReply, error) {
 	return s.multipassClient.Info(ctx, req)
 }
 
+func (s *server) SshInfo(ctx context.Context, req *common.SSHInfoRequest) (*common.SSHInfoReply, error) {
+	info, err := s.multipassClient.SSHInfo(ctx, req.GetInstanceName())
+	if err != nil {
+		return nil, err
+	}
+	return &common.SSHInfoReply{
+		Username:   info.Username,
+		Host:       info.Host,
+		Port:       info.Port,
+		PrivateKey: info.PrivKeyBase64,
+	}, nil
+}
+
+// Exec runs a command on an instance over ssh with the multipass key.
+func (s *server) Exec(ctx context.Context, req *common.ExecRequest) (*common.ExecReply, error) {
+	info, err := s.multipassClient.SSHInfo(ctx, req.GetInstanceName())
+	if err != nil {
+		return nil, err
+	}
+	return runCommand(ctx, info.Host, int(info.Port), info.Username, []byte(info.PrivKeyBase64), req.Command, req.Stdin)
+}
+
+// Forward pipes the stream to a tcp port of an instance, its ssh port unless
+// port is given in metadata.
+func (s *server) Forward(stream grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]) error {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	if !ok {
+		return fmt.Errorf("metadata not found in context")
+	}
+
+	instanceName := md.Get("instanceName")
+	if len(instanceName) == 0 {
+		return fmt.Errorf("instance name not found in context")
+	}
+
+	info, err := s.multipassClient.SSHInfo(stream.Context(), instanceName[0])
+	if err != nil {
+		return err
+	}
+
+	port := strconv.Itoa(int(info.Port))
+	if ports := md.Get("port"); len(ports) > 0 {
+		port = ports[0]
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(info.Host, port), forwardDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	log.Printf("forward connected: %s:%s", instanceName[0], port)
+	defer log.Printf("forward disconnected: %s:%s", instanceName[0], port)
+	return common.PipeForward(stream, conn, conn)
+}
+
 type windowSize struct {
 	sig    chan *windowSize
 	width  int64
@@ -143,6 +257,8 @@ func (s *shellRequestReader) Read(p []byte) (n int, err error) {
 
 type shellReplyWriter struct {
 	stream grpc.BidiStreamingServer[common.ShellRequest, common.ShellReply]
+	// sendMu is shared by the stdout and stderr writers of a stream
+	sendMu *sync.Mutex
 	isErr  bool
 }
 
@@ -153,7 +269,9 @@ func (s *shellReplyWriter) Write(p []byte) (n int, err error) {
 	} else {
 		reply.OutBuffer = p
 	}
+	s.sendMu.Lock()
 	err = s.stream.Send(reply)
+	s.sendMu.Unlock()
 	if err != nil {
 		return 0, err
 	}
@@ -179,8 +297,9 @@ func (s *server) Shell(stream grpc.BidiStreamingServer[common.ShellRequest, comm
 	}
 	w, _ := strconv.Atoi(width[0])
 
-	stdout := &shellReplyWriter{stream: stream}
-	stderr := &shellReplyWriter{stream: stream, isErr: true}
+	sendMu := &sync.Mutex{}
+	stdout := &shellReplyWriter{stream: stream, sendMu: sendMu}
+	stderr := &shellReplyWriter{stream: stream, sendMu: sendMu, isErr: true}
 	stdin := NewShellRequestReader(stream, h, w)
 
 	instanceName := md.Get("instanceName")
@@ -197,8 +316,10 @@ func (s *server) Shell(stream grpc.BidiStreamingServer[common.ShellRequest, comm
 	defer log.Printf("ssh disconnected: %s", id)
 	defer s.removeSSH(id)
 	ssh := NewSSH(info.Host, int(info.Port), info.Username, []byte(info.PrivKeyBase64), stdout, stderr, stdin, h, w)
-	s.addSSH(id, ssh)
+	s.addSSH(id, &shellSession{ssh: ssh, stderr: stderr})
 	log.Printf("ssh connected: %s", id)
+	metrics.ShellSessions.WithLabelValues("agent").Inc()
+	defer metrics.ShellSessions.WithLabelValues("agent").Dec()
 	go ssh.InheritSize(stdin.windowSize.sig)
 	if err = ssh.Start(); err != nil {
 		return err
@@ -213,13 +334,14 @@ func NewServer(addr string, multipassClient multipass.Client, state State) (Serv
 		return nil, err
 	}
 
-	var opts []grpc.ServerOption
+	opts := metrics.ServerOptions("agent")
 	grpcServer := grpc.NewServer(opts...)
 	server := &server{
 		multipassClient: multipassClient,
 		listener:        lis,
+		tunnelListener:  newTunnelListener(),
 		grpcServer:      grpcServer,
-		sshMap:          make(map[string]SSH),
+		sshMap:          make(map[string]*shellSession),
 		sshMu:           sync.RWMutex{},
 		state:           state,
 	}
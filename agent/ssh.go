@@ -1,6 +1,8 @@
 package agent
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,6 +10,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/erayarslan/multiverse/common"
+
 	goSsh "golang.org/x/crypto/ssh"
 )
 
@@ -48,24 +52,31 @@ loop:
 	}
 }
 
-func (s *ssh) Start() error {
-	defer close(s.closed)
-
-	signer, err := goSsh.ParsePrivateKey(s.pemBytes)
+func clientConfig(username string, pemBytes []byte) (*goSsh.ClientConfig, error) {
+	signer, err := goSsh.ParsePrivateKey(pemBytes)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	config := &goSsh.ClientConfig{
+	return &goSsh.ClientConfig{
 		Config: goSsh.Config{
 			Ciphers: []string{"chacha20-poly1305@openssh.com", "aes256-ctr"},
 		},
 		Timeout: 20 * time.Second,
-		User:    s.username,
+		User:    username,
 		Auth: []goSsh.AuthMethod{
 			goSsh.PublicKeys(signer),
 		},
 		HostKeyCallback: goSsh.InsecureIgnoreHostKey(), // nolint:gosec
+	}, nil
+}
+
+func (s *ssh) Start() error {
+	defer close(s.closed)
+
+	config, err := clientConfig(s.username, s.pemBytes)
+	if err != nil {
+		return err
 	}
 
 	s.client, err = goSsh.Dial("tcp", fmt.Sprintf("%s:%d", s.host, s.port), config)
@@ -114,9 +125,15 @@ func (s *ssh) Start() error {
 }
 
 func (s *ssh) Close() error {
-	err := s.session.Close()
-	if err == io.EOF {
-		err = nil
+	if s.client == nil {
+		return nil
+	}
+
+	var err error
+	if s.session != nil {
+		if err = s.session.Close(); err == io.EOF {
+			err = nil
+		}
 	}
 
 	return errors.Join(err, s.client.Close())
@@ -145,3 +162,51 @@ func NewSSH(host string,
 		closed:   make(chan struct{}, 1),
 	}
 }
+
+// runCommand runs command on an instance without a pty, feeding it stdin, and
+// returns its output and exit code. Cancelling ctx closes the connection.
+func runCommand(ctx context.Context, host string, port int, username string, pemBytes []byte,
+	command string, stdin []byte,
+) (*common.ExecReply, error) {
+	config, err := clientConfig(username, pemBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := goSsh.Dial("tcp", fmt.Sprintf("%s:%d", host, port), config)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+	})
+	defer stop()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = bytes.NewReader(stdin)
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	reply := &common.ExecReply{}
+	if err = session.Run(command); err != nil {
+		var e *goSsh.ExitError
+		if !errors.As(err, &e) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		reply.ExitCode = int32(e.ExitStatus())
+	}
+	reply.Stdout = stdout.Bytes()
+	reply.Stderr = stderr.Bytes()
+	return reply, nil
+}
@@ -14,20 +14,47 @@ import (
 	"github.com/shirou/gopsutil/v4/mem"
 )
 
+type Allocation struct {
+	CPUOvercommitRatio    float64
+	MemoryOvercommitRatio float64
+	DiskOvercommitRatio   float64
+	ReservedMemory        uint64
+	ReservedDisk          uint64
+	ReservedCPU           int32
+}
+
+type Snapshot struct {
+	Resource  *Resource
+	Instances []*Instance
+}
+
 type state struct {
 	multipassClient multipass.Client
-	stateChan       chan state
+	stateChan       chan Snapshot
+	refreshChan     chan struct{}
 	Resource        *Resource
 	Instances       []*Instance
+	allocation      Allocation
+	interval        time.Duration
 	stateMu         sync.RWMutex
 }
 
 type State interface {
-	Listen() <-chan state
+	Listen() <-chan Snapshot
 	GetState() *state
+	Snapshot() Snapshot
+	SetAllocation(allocation Allocation)
+	SetInterval(interval time.Duration)
+	Refresh()
 	Run()
 }
 
+type committed struct {
+	memory uint64
+	disk   uint64
+	cpu    int32
+}
+
 func (s *state) updateInstances() {
 	res, err := s.multipassClient.List(context.Background())
 	if err != nil {
@@ -53,6 +80,40 @@ func (s *state) GetState() *state {
 	return s
 }
 
+// Snapshot returns the latest resources and instances.
+func (s *state) Snapshot() Snapshot {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return Snapshot{Resource: s.Resource, Instances: s.Instances}
+}
+
+// committed sums what instances were given, stopped and suspended ones
+// included since they get it back once started. Deleted instances wait for a
+// purge and hold nothing.
+func (s *state) committed() (*committed, error) {
+	c := &committed{}
+	for _, instance := range s.Instances {
+		if instance.State == "Deleted" {
+			continue
+		}
+		allocated, err := s.multipassClient.Allocated(context.Background(), instance.Name)
+		if err != nil {
+			return nil, err
+		}
+		c.cpu += allocated.CPU
+		c.memory += allocated.Memory
+		c.disk += allocated.Disk
+	}
+	return c, nil
+}
+
+func allocatable(total uint64, reserved uint64, ratio float64) uint64 {
+	if reserved >= total {
+		return 0
+	}
+	return uint64(float64(total-reserved) * ratio)
+}
+
 func (s *state) updateResources() {
 	virtualMemoryStat, err := mem.VirtualMemory()
 	if err != nil {
@@ -80,42 +141,96 @@ func (s *state) updateResources() {
 		return
 	}
 
+	// a worker reporting nothing committed would be overcommitted, so the
+	// previous resources stay until multipass answers again
+	c, err := s.committed()
+	if err != nil {
+		log.Printf("error while getting committed resources: %v", err)
+		return
+	}
+	a := s.allocation
+
 	s.Resource = &Resource{
 		Cpu: &CPU{
-			Total:     totalCores,
-			Available: availableCore,
+			Total:       totalCores,
+			Available:   availableCore,
+			Allocatable: int32(allocatable(uint64(totalCores), uint64(a.ReservedCPU), a.CPUOvercommitRatio)),
+			Committed:   c.cpu,
 		},
 		Memory: &Memory{
-			Total:     virtualMemoryStat.Total,
-			Available: virtualMemoryStat.Available,
+			Total:       virtualMemoryStat.Total,
+			Available:   virtualMemoryStat.Available,
+			Allocatable: allocatable(virtualMemoryStat.Total, a.ReservedMemory, a.MemoryOvercommitRatio),
+			Committed:   c.memory,
 		},
 		Disk: &Disk{
-			Total:     diskUsage.Total,
-			Available: diskUsage.Free,
+			Total:       diskUsage.Total,
+			Available:   diskUsage.Free,
+			Allocatable: allocatable(diskUsage.Total, a.ReservedDisk, a.DiskOvercommitRatio),
+			Committed:   c.disk,
 		},
 	}
 }
 
+// Refresh polls multipass now instead of waiting for the next interval.
+func (s *state) Refresh() {
+	select {
+	case s.refreshChan <- struct{}{}:
+	default:
+	}
+}
+
 func (s *state) Run() {
+	s.stateMu.RLock()
+	interval := s.interval
+	s.stateMu.RUnlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		s.stateMu.Lock()
 		s.updateInstances()
 		s.updateResources()
-		s.stateChan <- *s
+		snapshot := Snapshot{Resource: s.Resource, Instances: s.Instances}
+		if s.interval != interval {
+			interval = s.interval
+			ticker.Reset(interval)
+		}
 		s.stateMu.Unlock()
-		time.Sleep(10 * time.Second)
+		s.stateChan <- snapshot
+
+		select {
+		case <-ticker.C:
+		case <-s.refreshChan:
+		}
 	}
 }
 
-func (s *state) Listen() <-chan state {
+// SetAllocation takes effect on the next resource update.
+func (s *state) SetAllocation(allocation Allocation) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.allocation = allocation
+}
+
+// SetInterval takes effect after the next poll.
+func (s *state) SetInterval(interval time.Duration) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.interval = interval
+}
+
+func (s *state) Listen() <-chan Snapshot {
 	return s.stateChan
 }
 
-func NewState(multipassClient multipass.Client) State {
+func NewState(multipassClient multipass.Client, allocation Allocation, interval time.Duration) State {
 	s := &state{
 		multipassClient: multipassClient,
 		stateMu:         sync.RWMutex{},
-		stateChan:       make(chan state),
+		stateChan:       make(chan Snapshot),
+		refreshChan:     make(chan struct{}, 1),
+		allocation:      allocation,
+		interval:        interval,
 	}
 	return s
 }
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var errTunnelClosed = errors.New("tunnel closed")
+
+type tunnelAddr struct{}
+
+func (tunnelAddr) Network() string { return "tunnel" }
+func (tunnelAddr) String() string  { return "tunnel" }
+
+// tunnelListener hands connections tunneled through master to the agent grpc
+// server next to its tcp listener.
+type tunnelListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newTunnelListener() *tunnelListener {
+	return &tunnelListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+func (l *tunnelListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *tunnelListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *tunnelListener) Addr() net.Addr {
+	return tunnelAddr{}
+}
+
+func (l *tunnelListener) serve(conn net.Conn) error {
+	select {
+	case l.conns <- conn:
+		return nil
+	case <-l.closed:
+		return net.ErrClosed
+	}
+}
+
+// NewTunnelClient creates an agent client whose only connection is conn, it
+// fails once the tunnel closes and a new tunnel brings a new client.
+func NewTunnelClient(conn net.Conn) (Client, error) {
+	var once sync.Once
+	dialer := func(_ context.Context, _ string) (net.Conn, error) {
+		var dialed net.Conn
+		once.Do(func() {
+			dialed = conn
+		})
+		if dialed == nil {
+			return nil, errTunnelClosed
+		}
+		return dialed, nil
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer),
+		// going idle closes the only connection there is
+		grpc.WithIdleTimeout(0),
+	}
+	grpcConn, err := grpc.NewClient("passthrough:///tunnel", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		conn:   grpcConn,
+		client: NewRpcClient(grpcConn),
+	}, nil
+}
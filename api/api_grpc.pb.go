@@ -20,11 +20,27 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Rpc_Instances_FullMethodName = "/api.Rpc/instances"
-	Rpc_Nodes_FullMethodName     = "/api.Rpc/nodes"
-	Rpc_Info_FullMethodName      = "/api.Rpc/info"
-	Rpc_Shell_FullMethodName     = "/api.Rpc/shell"
-	Rpc_Launch_FullMethodName    = "/api.Rpc/launch"
+	Rpc_Instances_FullMethodName         = "/api.Rpc/instances"
+	Rpc_Nodes_FullMethodName             = "/api.Rpc/nodes"
+	Rpc_Info_FullMethodName              = "/api.Rpc/info"
+	Rpc_Shell_FullMethodName             = "/api.Rpc/shell"
+	Rpc_Launch_FullMethodName            = "/api.Rpc/launch"
+	Rpc_Taint_FullMethodName             = "/api.Rpc/taint"
+	Rpc_Untaint_FullMethodName           = "/api.Rpc/untaint"
+	Rpc_Cordon_FullMethodName            = "/api.Rpc/cordon"
+	Rpc_Uncordon_FullMethodName          = "/api.Rpc/uncordon"
+	Rpc_Drain_FullMethodName             = "/api.Rpc/drain"
+	Rpc_Watch_FullMethodName             = "/api.Rpc/watch"
+	Rpc_WebhookDeliveries_FullMethodName = "/api.Rpc/webhook_deliveries"
+	Rpc_History_FullMethodName           = "/api.Rpc/history"
+	Rpc_Stop_FullMethodName              = "/api.Rpc/stop"
+	Rpc_Version_FullMethodName           = "/api.Rpc/version"
+	Rpc_Forward_FullMethodName           = "/api.Rpc/forward"
+	Rpc_SshInfo_FullMethodName           = "/api.Rpc/ssh_info"
+	Rpc_Keys_FullMethodName              = "/api.Rpc/keys"
+	Rpc_AddKey_FullMethodName            = "/api.Rpc/add_key"
+	Rpc_RemoveKey_FullMethodName         = "/api.Rpc/remove_key"
+	Rpc_PushKeys_FullMethodName          = "/api.Rpc/push_keys"
 )
 
 // RpcClient is the client API for Rpc service.
@@ -36,6 +52,22 @@ type RpcClient interface {
 	Info(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoReply, error)
 	Shell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[common.ShellRequest, common.ShellReply], error)
 	Launch(ctx context.Context, in *common.LaunchRequest, opts ...grpc.CallOption) (*common.LaunchReply, error)
+	Taint(ctx context.Context, in *TaintRequest, opts ...grpc.CallOption) (*TaintReply, error)
+	Untaint(ctx context.Context, in *UntaintRequest, opts ...grpc.CallOption) (*UntaintReply, error)
+	Cordon(ctx context.Context, in *CordonRequest, opts ...grpc.CallOption) (*CordonReply, error)
+	Uncordon(ctx context.Context, in *UncordonRequest, opts ...grpc.CallOption) (*UncordonReply, error)
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[common.Event], error)
+	WebhookDeliveries(ctx context.Context, in *GetWebhookDeliveriesRequest, opts ...grpc.CallOption) (*GetWebhookDeliveriesReply, error)
+	History(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*common.History, error)
+	Stop(ctx context.Context, in *common.StopRequest, opts ...grpc.CallOption) (*common.StopReply, error)
+	Version(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionReply, error)
+	Forward(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame], error)
+	SshInfo(ctx context.Context, in *common.SSHInfoRequest, opts ...grpc.CallOption) (*common.SSHInfoReply, error)
+	Keys(ctx context.Context, in *GetKeysRequest, opts ...grpc.CallOption) (*GetKeysReply, error)
+	AddKey(ctx context.Context, in *AddKeyRequest, opts ...grpc.CallOption) (*AddKeyReply, error)
+	RemoveKey(ctx context.Context, in *RemoveKeyRequest, opts ...grpc.CallOption) (*RemoveKeyReply, error)
+	PushKeys(ctx context.Context, in *PushKeysRequest, opts ...grpc.CallOption) (*PushKeysReply, error)
 }
 
 type rpcClient struct {
@@ -99,6 +131,178 @@ func (c *rpcClient) Launch(ctx context.Context, in *common.LaunchRequest, opts .
 	return out, nil
 }
 
+func (c *rpcClient) Taint(ctx context.Context, in *TaintRequest, opts ...grpc.CallOption) (*TaintReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TaintReply)
+	err := c.cc.Invoke(ctx, Rpc_Taint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Untaint(ctx context.Context, in *UntaintRequest, opts ...grpc.CallOption) (*UntaintReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UntaintReply)
+	err := c.cc.Invoke(ctx, Rpc_Untaint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Cordon(ctx context.Context, in *CordonRequest, opts ...grpc.CallOption) (*CordonReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CordonReply)
+	err := c.cc.Invoke(ctx, Rpc_Cordon_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Uncordon(ctx context.Context, in *UncordonRequest, opts ...grpc.CallOption) (*UncordonReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UncordonReply)
+	err := c.cc.Invoke(ctx, Rpc_Uncordon_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainReply)
+	err := c.cc.Invoke(ctx, Rpc_Drain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[common.Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Rpc_ServiceDesc.Streams[1], Rpc_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, common.Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_WatchClient = grpc.ServerStreamingClient[common.Event]
+
+func (c *rpcClient) WebhookDeliveries(ctx context.Context, in *GetWebhookDeliveriesRequest, opts ...grpc.CallOption) (*GetWebhookDeliveriesReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWebhookDeliveriesReply)
+	err := c.cc.Invoke(ctx, Rpc_WebhookDeliveries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) History(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*common.History, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(common.History)
+	err := c.cc.Invoke(ctx, Rpc_History_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Stop(ctx context.Context, in *common.StopRequest, opts ...grpc.CallOption) (*common.StopReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(common.StopReply)
+	err := c.cc.Invoke(ctx, Rpc_Stop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Version(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVersionReply)
+	err := c.cc.Invoke(ctx, Rpc_Version_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Forward(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Rpc_ServiceDesc.Streams[2], Rpc_Forward_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[common.ForwardFrame, common.ForwardFrame]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_ForwardClient = grpc.BidiStreamingClient[common.ForwardFrame, common.ForwardFrame]
+
+func (c *rpcClient) SshInfo(ctx context.Context, in *common.SSHInfoRequest, opts ...grpc.CallOption) (*common.SSHInfoReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(common.SSHInfoReply)
+	err := c.cc.Invoke(ctx, Rpc_SshInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) Keys(ctx context.Context, in *GetKeysRequest, opts ...grpc.CallOption) (*GetKeysReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetKeysReply)
+	err := c.cc.Invoke(ctx, Rpc_Keys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) AddKey(ctx context.Context, in *AddKeyRequest, opts ...grpc.CallOption) (*AddKeyReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddKeyReply)
+	err := c.cc.Invoke(ctx, Rpc_AddKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) RemoveKey(ctx context.Context, in *RemoveKeyRequest, opts ...grpc.CallOption) (*RemoveKeyReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveKeyReply)
+	err := c.cc.Invoke(ctx, Rpc_RemoveKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rpcClient) PushKeys(ctx context.Context, in *PushKeysRequest, opts ...grpc.CallOption) (*PushKeysReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PushKeysReply)
+	err := c.cc.Invoke(ctx, Rpc_PushKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RpcServer is the server API for Rpc service.
 // All implementations must embed UnimplementedRpcServer
 // for forward compatibility.
@@ -108,6 +312,22 @@ type RpcServer interface {
 	Info(context.Context, *GetInfoRequest) (*GetInfoReply, error)
 	Shell(grpc.BidiStreamingServer[common.ShellRequest, common.ShellReply]) error
 	Launch(context.Context, *common.LaunchRequest) (*common.LaunchReply, error)
+	Taint(context.Context, *TaintRequest) (*TaintReply, error)
+	Untaint(context.Context, *UntaintRequest) (*UntaintReply, error)
+	Cordon(context.Context, *CordonRequest) (*CordonReply, error)
+	Uncordon(context.Context, *UncordonRequest) (*UncordonReply, error)
+	Drain(context.Context, *DrainRequest) (*DrainReply, error)
+	Watch(*WatchRequest, grpc.ServerStreamingServer[common.Event]) error
+	WebhookDeliveries(context.Context, *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesReply, error)
+	History(context.Context, *GetHistoryRequest) (*common.History, error)
+	Stop(context.Context, *common.StopRequest) (*common.StopReply, error)
+	Version(context.Context, *GetVersionRequest) (*GetVersionReply, error)
+	Forward(grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]) error
+	SshInfo(context.Context, *common.SSHInfoRequest) (*common.SSHInfoReply, error)
+	Keys(context.Context, *GetKeysRequest) (*GetKeysReply, error)
+	AddKey(context.Context, *AddKeyRequest) (*AddKeyReply, error)
+	RemoveKey(context.Context, *RemoveKeyRequest) (*RemoveKeyReply, error)
+	PushKeys(context.Context, *PushKeysRequest) (*PushKeysReply, error)
 	mustEmbedUnimplementedRpcServer()
 }
 
@@ -133,6 +353,54 @@ func (UnimplementedRpcServer) Shell(grpc.BidiStreamingServer[common.ShellRequest
 func (UnimplementedRpcServer) Launch(context.Context, *common.LaunchRequest) (*common.LaunchReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Launch not implemented")
 }
+func (UnimplementedRpcServer) Taint(context.Context, *TaintRequest) (*TaintReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Taint not implemented")
+}
+func (UnimplementedRpcServer) Untaint(context.Context, *UntaintRequest) (*UntaintReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Untaint not implemented")
+}
+func (UnimplementedRpcServer) Cordon(context.Context, *CordonRequest) (*CordonReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cordon not implemented")
+}
+func (UnimplementedRpcServer) Uncordon(context.Context, *UncordonRequest) (*UncordonReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Uncordon not implemented")
+}
+func (UnimplementedRpcServer) Drain(context.Context, *DrainRequest) (*DrainReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Drain not implemented")
+}
+func (UnimplementedRpcServer) Watch(*WatchRequest, grpc.ServerStreamingServer[common.Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedRpcServer) WebhookDeliveries(context.Context, *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WebhookDeliveries not implemented")
+}
+func (UnimplementedRpcServer) History(context.Context, *GetHistoryRequest) (*common.History, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
+func (UnimplementedRpcServer) Stop(context.Context, *common.StopRequest) (*common.StopReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+func (UnimplementedRpcServer) Version(context.Context, *GetVersionRequest) (*GetVersionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
+}
+func (UnimplementedRpcServer) Forward(grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]) error {
+	return status.Errorf(codes.Unimplemented, "method Forward not implemented")
+}
+func (UnimplementedRpcServer) SshInfo(context.Context, *common.SSHInfoRequest) (*common.SSHInfoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SshInfo not implemented")
+}
+func (UnimplementedRpcServer) Keys(context.Context, *GetKeysRequest) (*GetKeysReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Keys not implemented")
+}
+func (UnimplementedRpcServer) AddKey(context.Context, *AddKeyRequest) (*AddKeyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddKey not implemented")
+}
+func (UnimplementedRpcServer) RemoveKey(context.Context, *RemoveKeyRequest) (*RemoveKeyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveKey not implemented")
+}
+func (UnimplementedRpcServer) PushKeys(context.Context, *PushKeysRequest) (*PushKeysReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PushKeys not implemented")
+}
 func (UnimplementedRpcServer) mustEmbedUnimplementedRpcServer() {}
 func (UnimplementedRpcServer) testEmbeddedByValue()             {}
 
@@ -233,6 +501,276 @@ func _Rpc_Launch_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Rpc_Taint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Taint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Taint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Taint(ctx, req.(*TaintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Untaint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UntaintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Untaint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Untaint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Untaint(ctx, req.(*UntaintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Cordon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CordonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Cordon(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Cordon_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Cordon(ctx, req.(*CordonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Uncordon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UncordonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Uncordon(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Uncordon_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Uncordon(ctx, req.(*UncordonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Drain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RpcServer).Watch(m, &grpc.GenericServerStream[WatchRequest, common.Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_WatchServer = grpc.ServerStreamingServer[common.Event]
+
+func _Rpc_WebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).WebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_WebhookDeliveries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).WebhookDeliveries(ctx, req.(*GetWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_History_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).History(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.StopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Stop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Stop(ctx, req.(*common.StopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Version_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Version(ctx, req.(*GetVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Forward_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RpcServer).Forward(&grpc.GenericServerStream[common.ForwardFrame, common.ForwardFrame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_ForwardServer = grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]
+
+func _Rpc_SshInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.SSHInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).SshInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_SshInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).SshInfo(ctx, req.(*common.SSHInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_Keys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).Keys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_Keys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).Keys(ctx, req.(*GetKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_AddKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).AddKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_AddKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).AddKey(ctx, req.(*AddKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_RemoveKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).RemoveKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_RemoveKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).RemoveKey(ctx, req.(*RemoveKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rpc_PushKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RpcServer).PushKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Rpc_PushKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RpcServer).PushKeys(ctx, req.(*PushKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Rpc_ServiceDesc is the grpc.ServiceDesc for Rpc service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -256,6 +794,62 @@ var Rpc_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "launch",
 			Handler:    _Rpc_Launch_Handler,
 		},
+		{
+			MethodName: "taint",
+			Handler:    _Rpc_Taint_Handler,
+		},
+		{
+			MethodName: "untaint",
+			Handler:    _Rpc_Untaint_Handler,
+		},
+		{
+			MethodName: "cordon",
+			Handler:    _Rpc_Cordon_Handler,
+		},
+		{
+			MethodName: "uncordon",
+			Handler:    _Rpc_Uncordon_Handler,
+		},
+		{
+			MethodName: "drain",
+			Handler:    _Rpc_Drain_Handler,
+		},
+		{
+			MethodName: "webhook_deliveries",
+			Handler:    _Rpc_WebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "history",
+			Handler:    _Rpc_History_Handler,
+		},
+		{
+			MethodName: "stop",
+			Handler:    _Rpc_Stop_Handler,
+		},
+		{
+			MethodName: "version",
+			Handler:    _Rpc_Version_Handler,
+		},
+		{
+			MethodName: "ssh_info",
+			Handler:    _Rpc_SshInfo_Handler,
+		},
+		{
+			MethodName: "keys",
+			Handler:    _Rpc_Keys_Handler,
+		},
+		{
+			MethodName: "add_key",
+			Handler:    _Rpc_AddKey_Handler,
+		},
+		{
+			MethodName: "remove_key",
+			Handler:    _Rpc_RemoveKey_Handler,
+		},
+		{
+			MethodName: "push_keys",
+			Handler:    _Rpc_PushKeys_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -264,6 +858,17 @@ var Rpc_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "watch",
+			Handler:       _Rpc_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "forward",
+			Handler:       _Rpc_Forward_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "api/api.proto",
 }
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// privilegedMethods hand out or grant access to instances, so they are
+// refused on an api serving every caller.
+var privilegedMethods = map[string]bool{
+	Rpc_SshInfo_FullMethodName:   true,
+	Rpc_AddKey_FullMethodName:    true,
+	Rpc_RemoveKey_FullMethodName: true,
+	Rpc_PushKeys_FullMethodName:  true,
+}
+
+// ServerAuth secures the api server, the counterpart of Credentials. Without
+// a token every caller is served, without a cert the api is plain text.
+type ServerAuth struct {
+	Token        string
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// LoadToken reads a token file, empty paths meaning no token.
+func LoadToken(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file is empty: %s", path)
+	}
+	return token, nil
+}
+
+// Authenticated tells whether callers have to prove who they are.
+func (a *ServerAuth) Authenticated() bool {
+	return a != nil && (a.Token != "" || a.ClientCAFile != "")
+}
+
+func (a *ServerAuth) secure() bool {
+	return a != nil && a.CertFile != ""
+}
+
+func (a *ServerAuth) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if a.ClientCAFile != "" {
+		pem, err := os.ReadFile(a.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in client ca file: %s", a.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// GatewayTLSConfig serves the gateway with the api certificate, nil when the
+// api is plain text. Browsers show no client certificate, so none is asked.
+func (a *ServerAuth) GatewayTLSConfig() (*tls.Config, error) {
+	if !a.secure() {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
+
+// checkPrivileged refuses privileged methods when callers are not
+// authenticated.
+func (a *ServerAuth) checkPrivileged(method string) error {
+	if privilegedMethods[method] && !a.Authenticated() {
+		return status.Errorf(codes.PermissionDenied,
+			"%s needs an authenticated api, set -api-token-file or -api-tls-client-ca-file on master", path.Base(method))
+	}
+	return nil
+}
+
+// checkToken admits calls carrying the api token as bearer token.
+func (a *ServerAuth) checkToken(ctx context.Context) error {
+	if a == nil || a.Token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(authorization, "Bearer ")
+		if ok && a.ValidToken(token) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing api token")
+}
+
+// ValidToken compares token with the api token in constant time, false when
+// master has no token.
+func (a *ServerAuth) ValidToken(token string) bool {
+	if a == nil || a.Token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) == 1
+}
+
+func (a *ServerAuth) tokenUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := a.checkPrivileged(info.FullMethod); err != nil {
+		return nil, err
+	}
+	if err := a.checkToken(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *ServerAuth) tokenStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := a.checkPrivileged(info.FullMethod); err != nil {
+		return err
+	}
+	if err := a.checkToken(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (a *ServerAuth) serverOptions() ([]grpc.ServerOption, error) {
+	if !a.secure() {
+		return nil, nil
+	}
+	tlsConfig, err := a.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(tlsConfig))}, nil
+}
+
+// LocalDialOption connects a component of master, like the gateway, to its
+// own api server, trusting only the certificate the server presents and
+// showing it as client certificate. Callers still bring their own token.
+func (a *ServerAuth) LocalDialOption() (grpc.DialOption, error) {
+	if !a.secure() {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+	cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		// the api listens on a wildcard or loopback addr the certificate need
+		// not name, so the peer is pinned to the certificate itself
+		InsecureSkipVerify: true, // nolint:gosec
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], cert.Certificate[0]) {
+				return fmt.Errorf("api server presented an unexpected certificate")
+			}
+			return nil
+		},
+	})), nil
+}
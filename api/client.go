@@ -2,11 +2,14 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	osSignal "os/signal"
+	"strconv"
 
 	"github.com/erayarslan/multiverse/common"
 
@@ -14,12 +17,13 @@ import (
 
 	"golang.org/x/term"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 )
 
 type client struct {
-	conn   *grpc.ClientConn
+	conn   *failoverConn
 	client RpcClient
 }
 
@@ -29,6 +33,22 @@ type Client interface {
 	Info(ctx context.Context) (*GetInfoReply, error)
 	Shell(ctx context.Context, instanceName string) error
 	Launch(ctx context.Context, launchRequest *common.LaunchRequest) (*common.LaunchReply, error)
+	Taint(ctx context.Context, nodeName string, taint *common.Taint) error
+	Untaint(ctx context.Context, nodeName string, key string) error
+	Cordon(ctx context.Context, nodeName string) error
+	Uncordon(ctx context.Context, nodeName string) error
+	Drain(ctx context.Context, drainRequest *DrainRequest) (*DrainReply, error)
+	WebhookDeliveries(ctx context.Context) (*GetWebhookDeliveriesReply, error)
+	Watch(ctx context.Context, types []common.EventType, callback func(event *common.Event) error) error
+	History(ctx context.Context, historyRequest *GetHistoryRequest) (*common.History, error)
+	Stop(ctx context.Context, stopRequest *common.StopRequest) (*common.StopReply, error)
+	Version(ctx context.Context) (*GetVersionReply, error)
+	Forward(ctx context.Context, instanceName string, port int, r io.Reader, w io.Writer) error
+	SSHInfo(ctx context.Context, instanceName string) (*common.SSHInfoReply, error)
+	Keys(ctx context.Context) (*GetKeysReply, error)
+	AddKey(ctx context.Context, name string, publicKey string) (*AddKeyReply, error)
+	RemoveKey(ctx context.Context, name string) (*RemoveKeyReply, error)
+	PushKeys(ctx context.Context, instanceName string) (*PushKeysReply, error)
 	Close() error
 }
 
@@ -48,6 +68,100 @@ func (c *client) Launch(ctx context.Context, launchRequest *common.LaunchRequest
 	return c.client.Launch(ctx, launchRequest)
 }
 
+func (c *client) Taint(ctx context.Context, nodeName string, taint *common.Taint) error {
+	_, err := c.client.Taint(ctx, &TaintRequest{NodeName: nodeName, Taint: taint})
+	return err
+}
+
+func (c *client) Untaint(ctx context.Context, nodeName string, key string) error {
+	_, err := c.client.Untaint(ctx, &UntaintRequest{NodeName: nodeName, Key: key})
+	return err
+}
+
+func (c *client) Cordon(ctx context.Context, nodeName string) error {
+	_, err := c.client.Cordon(ctx, &CordonRequest{NodeName: nodeName})
+	return err
+}
+
+func (c *client) Uncordon(ctx context.Context, nodeName string) error {
+	_, err := c.client.Uncordon(ctx, &UncordonRequest{NodeName: nodeName})
+	return err
+}
+
+func (c *client) Drain(ctx context.Context, drainRequest *DrainRequest) (*DrainReply, error) {
+	return c.client.Drain(ctx, drainRequest)
+}
+
+func (c *client) Watch(ctx context.Context, types []common.EventType, callback func(event *common.Event) error) error {
+	stream, err := c.client.Watch(ctx, &WatchRequest{Types: types})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = callback(event); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *client) WebhookDeliveries(ctx context.Context) (*GetWebhookDeliveriesReply, error) {
+	return c.client.WebhookDeliveries(ctx, &GetWebhookDeliveriesRequest{})
+}
+
+func (c *client) History(ctx context.Context, historyRequest *GetHistoryRequest) (*common.History, error) {
+	return c.client.History(ctx, historyRequest)
+}
+
+func (c *client) Stop(ctx context.Context, stopRequest *common.StopRequest) (*common.StopReply, error) {
+	return c.client.Stop(ctx, stopRequest)
+}
+
+func (c *client) Version(ctx context.Context) (*GetVersionReply, error) {
+	return c.client.Version(ctx, &GetVersionRequest{})
+}
+
+func (c *client) SSHInfo(ctx context.Context, instanceName string) (*common.SSHInfoReply, error) {
+	return c.client.SshInfo(ctx, &common.SSHInfoRequest{InstanceName: instanceName})
+}
+
+func (c *client) Keys(ctx context.Context) (*GetKeysReply, error) {
+	return c.client.Keys(ctx, &GetKeysRequest{})
+}
+
+func (c *client) AddKey(ctx context.Context, name string, publicKey string) (*AddKeyReply, error) {
+	return c.client.AddKey(ctx, &AddKeyRequest{Name: name, PublicKey: publicKey})
+}
+
+func (c *client) RemoveKey(ctx context.Context, name string) (*RemoveKeyReply, error) {
+	return c.client.RemoveKey(ctx, &RemoveKeyRequest{Name: name})
+}
+
+func (c *client) PushKeys(ctx context.Context, instanceName string) (*PushKeysReply, error) {
+	return c.client.PushKeys(ctx, &PushKeysRequest{InstanceName: instanceName})
+}
+
+// Forward connects r and w to a tcp port of an instance through master,
+// zero port being its ssh port.
+func (c *client) Forward(ctx context.Context, instanceName string, port int, r io.Reader, w io.Writer) error {
+	md := metadata.Pairs("instanceName", instanceName)
+	if port > 0 {
+		md.Set("port", strconv.Itoa(port))
+	}
+	stream, err := c.client.Forward(metadata.NewOutgoingContext(ctx, md))
+	if err != nil {
+		return err
+	}
+	return common.PipeForward(stream, r, w)
+}
+
 func (c *client) Info(ctx context.Context) (*GetInfoReply, error) {
 	return c.client.Info(ctx, &GetInfoRequest{})
 }
@@ -155,12 +269,90 @@ func (c *client) Shell(ctx context.Context, instanceName string) error {
 	})
 }
 
-func NewClient(addr string) (Client, error) {
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-	conn, err := grpc.NewClient(addr, opts...)
+type Credentials struct {
+	Token    string
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (t *tokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+func dialOptions(creds *Credentials) ([]grpc.DialOption, error) {
+	if creds == nil {
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+
+	opts := make([]grpc.DialOption, 0, 2)
+	secure := creds.CAFile != "" || creds.CertFile != ""
+	if secure {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if creds.CAFile != "" {
+			pem, err := os.ReadFile(creds.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in ca file: %s", creds.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if creds.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(creds.CertFile, creds.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	if creds.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(&tokenCredentials{token: creds.Token, secure: secure}))
+	}
+
+	return opts, nil
+}
+
+// NewClient connects to the api server at addr, a comma separated list of
+// masters failing over to whichever leads.
+func NewClient(addr string, creds *Credentials) (Client, error) {
+	opts, err := dialOptions(creds)
 	if err != nil {
 		return nil, err
 	}
+	opts = append(opts,
+		grpc.WithChainUnaryInterceptor(versionUnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(versionStreamClientInterceptor),
+	)
+	addrs := common.ParseAddrs(addr)
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no api server addr to connect")
+	}
+
+	conn := &failoverConn{}
+	for _, addr := range addrs {
+		grpcConn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn.conns = append(conn.conns, grpcConn)
+	}
 	rpcClient := NewRpcClient(conn)
 	return &client{
 		conn:   conn,
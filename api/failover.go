@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	leaderProbeTimeout = 5 * time.Second
+	notLeaderMessage   = "master is not the leader"
+)
+
+// failoverConn sends calls to the leading master among several, finding the
+// leader again once a master is down or no longer leads.
+type failoverConn struct {
+	conns   []*grpc.ClientConn
+	current int
+	mu      sync.Mutex
+	known   bool
+}
+
+func (f *failoverConn) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	conn, err := f.leader(ctx)
+	if err != nil {
+		return err
+	}
+	err = conn.Invoke(ctx, method, args, reply, opts...)
+	if len(f.conns) == 1 || status.Code(err) != codes.Unavailable {
+		return err
+	}
+
+	// the leader may have moved, but only a refused call is known not to
+	// have run, so anything else is left to the caller to repeat
+	f.forget(conn)
+	if !isNotLeader(err) {
+		return err
+	}
+	if conn, err = f.leader(ctx); err != nil {
+		return err
+	}
+	return conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+// isNotLeader tells a follower refusing a call before running it.
+func isNotLeader(err error) bool {
+	s, ok := status.FromError(err)
+	return ok && s.Code() == codes.Unavailable && s.Message() == notLeaderMessage
+}
+
+// NewStream opens streams on the leader, a stream broken by failover is not
+// reopened since its calls may not be repeatable.
+func (f *failoverConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	conn, err := f.leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.NewStream(ctx, desc, method, opts...)
+}
+
+func (f *failoverConn) forget(conn *grpc.ClientConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conns[f.current] == conn {
+		f.known = false
+		f.current = (f.current + 1) % len(f.conns)
+	}
+}
+
+// leader returns the conn of the leading master, asking the masters in turn
+// until one says it leads. A single master is used as it is.
+func (f *failoverConn) leader(ctx context.Context) (*grpc.ClientConn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.conns) == 1 || f.known {
+		return f.conns[f.current], nil
+	}
+
+	errs := make([]error, 0, len(f.conns))
+	for i := range f.conns {
+		index := (f.current + i) % len(f.conns)
+		conn := f.conns[index]
+
+		probeCtx, cancel := context.WithTimeout(ctx, leaderProbeTimeout)
+		reply := &GetVersionReply{}
+		err := conn.Invoke(probeCtx, Rpc_Version_FullMethodName, &GetVersionRequest{}, reply)
+		cancel()
+
+		// masters older than versioning run alone and always lead
+		if err == nil && reply.Leader || status.Code(err) == codes.Unimplemented {
+			f.current, f.known = index, true
+			return conn, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("not the leader")
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", conn.Target(), err))
+	}
+	return nil, status.Errorf(codes.Unavailable, "no leading master found: %v", errors.Join(errs...))
+}
+
+func (f *failoverConn) Close() error {
+	errs := make([]error, 0, len(f.conns))
+	for _, conn := range f.conns {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
+}
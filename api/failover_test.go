@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// fakeMaster answers version with its leadership and nodes with nodesErr.
+type fakeMaster struct {
+	UnimplementedRpcServer
+	nodesErr error
+	calls    atomic.Int32
+	leader   bool
+}
+
+func (m *fakeMaster) Version(context.Context, *GetVersionRequest) (*GetVersionReply, error) {
+	return &GetVersionReply{Leader: m.leader}, nil
+}
+
+func (m *fakeMaster) Nodes(context.Context, *GetNodesRequest) (*GetNodesReply, error) {
+	m.calls.Add(1)
+	if m.nodesErr != nil {
+		return nil, m.nodesErr
+	}
+	return &GetNodesReply{}, nil
+}
+
+func serveMaster(t *testing.T, m *fakeMaster) *grpc.ClientConn {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	RegisterRpcServer(s, m)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestFailoverConn(t *testing.T) {
+	notLeader := status.Error(codes.Unavailable, notLeaderMessage)
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+
+	tests := []struct {
+		wantErr   error
+		name      string
+		masters   []*fakeMaster
+		wantCalls []int32
+	}{
+		{
+			name:      "leader found",
+			masters:   []*fakeMaster{{}, {leader: true}, {}},
+			wantCalls: []int32{0, 1, 0},
+		},
+		{
+			name:      "refused by a former leader is retried",
+			masters:   []*fakeMaster{{leader: true, nodesErr: notLeader}, {leader: true}},
+			wantCalls: []int32{1, 1},
+		},
+		{
+			name:      "unavailable after sending is not retried",
+			masters:   []*fakeMaster{{leader: true, nodesErr: unavailable}, {leader: true}},
+			wantCalls: []int32{1, 0},
+			wantErr:   unavailable,
+		},
+		{
+			name:      "other errors are not retried",
+			masters:   []*fakeMaster{{leader: true, nodesErr: status.Error(codes.NotFound, "node")}, {leader: true}},
+			wantCalls: []int32{1, 0},
+			wantErr:   status.Error(codes.NotFound, "node"),
+		},
+		{
+			name:      "no leader",
+			masters:   []*fakeMaster{{}, {}},
+			wantCalls: []int32{0, 0},
+			wantErr:   status.Error(codes.Unavailable, ""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &failoverConn{}
+			for _, m := range tt.masters {
+				conn.conns = append(conn.conns, serveMaster(t, m))
+			}
+			defer conn.Close()
+
+			_, err := NewRpcClient(conn).Nodes(context.Background(), &GetNodesRequest{})
+
+			if status.Code(err) != status.Code(tt.wantErr) {
+				t.Errorf("Nodes() error = %v, want %v", err, tt.wantErr)
+			}
+			for i, m := range tt.masters {
+				if got := m.calls.Load(); got != tt.wantCalls[i] {
+					t.Errorf("master %d got %d calls, want %d", i, got, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}
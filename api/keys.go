@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erayarslan/multiverse/agent"
+	"github.com/erayarslan/multiverse/cluster"
+	"github.com/erayarslan/multiverse/common"
+	"github.com/erayarslan/multiverse/multipass"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	keyExecTimeout    = 30 * time.Second
+	cloudConfigHeader = "#cloud-config"
+)
+
+// pushKeysScript appends the authorized_keys lines read from stdin unless a
+// line with the same key is there already.
+const pushKeysScript = `set -e
+mkdir -p ~/.ssh && chmod 700 ~/.ssh
+touch ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys
+while read -r line; do
+  set -- $line
+  awk -v k="$2" '$2 == k { found = 1 } END { exit !found }' ~/.ssh/authorized_keys || echo "$line" >> ~/.ssh/authorized_keys
+done`
+
+// revokeKeysScript drops every authorized_keys line with a key read from stdin.
+const revokeKeysScript = `set -e
+[ -f ~/.ssh/authorized_keys ] || exit 0
+while read -r line; do
+  set -- $line
+  awk -v k="$2" '$2 != k' ~/.ssh/authorized_keys > ~/.ssh/authorized_keys.multiverse
+  cat ~/.ssh/authorized_keys.multiverse > ~/.ssh/authorized_keys
+  rm ~/.ssh/authorized_keys.multiverse
+done`
+
+type keyTarget struct {
+	agentClient agent.Client
+	instance    string
+	node        string
+	exec        bool
+}
+
+func newKey(key *cluster.UserKey) *Key {
+	return &Key{
+		Name:        key.Name,
+		PublicKey:   key.PublicKey,
+		Fingerprint: key.Fingerprint,
+		Added:       timestamppb.New(key.Added),
+	}
+}
+
+// cloudInit returns the cloud-init user data of a launch, userData with the
+// registered keys added to the default user. Only cloud-config carries them,
+// so other user data is refused while keys are registered.
+func (s *server) cloudInit(userData string) (string, error) {
+	keys := s.clusterServer.UserKeys()
+	if len(keys) == 0 {
+		return userData, nil
+	}
+	if userData == "" {
+		userData = cloudConfigHeader + "\n"
+	}
+	body, ok := strings.CutPrefix(userData, cloudConfigHeader+"\n")
+	if !ok {
+		return "", fmt.Errorf("cloud-init user data must start with %s to carry the registered keys", cloudConfigHeader)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return "", fmt.Errorf("invalid cloud-config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	config := doc.Content[0]
+	if config.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("invalid cloud-config: not a mapping")
+	}
+
+	var authorized *yaml.Node
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		if config.Content[i].Value == "ssh_authorized_keys" {
+			authorized = config.Content[i+1]
+		}
+	}
+	if authorized == nil {
+		authorized = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		config.Content = append(config.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "ssh_authorized_keys"}, authorized)
+	}
+	if authorized.Kind != yaml.SequenceNode {
+		return "", fmt.Errorf("invalid cloud-config: ssh_authorized_keys is not a list")
+	}
+	for _, key := range keys {
+		authorized.Content = append(authorized.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.AuthorizedKey()})
+	}
+
+	merged, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	return cloudConfigHeader + "\n" + string(merged), nil
+}
+
+// keyTargets lists running instances, only instanceName when given.
+func (s *server) keyTargets(instanceName string) ([]*keyTarget, error) {
+	targets := make([]*keyTarget, 0)
+	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
+		for _, instance := range workerInfo.State.Instances {
+			if instanceName != "" && instance.Name != instanceName {
+				continue
+			}
+			if instance.State != multipass.InstanceStatus_RUNNING.ToString() {
+				continue
+			}
+			targets = append(targets, &keyTarget{
+				agentClient: workerInfo.AgentClient,
+				instance:    instance.Name,
+				node:        workerInfo.NodeName,
+				exec:        workerInfo.Version.Has(common.CapabilityExec),
+			})
+		}
+		return true
+	})
+	if instanceName != "" && len(targets) == 0 {
+		return nil, fmt.Errorf("running instance not found: %s", instanceName)
+	}
+	return targets, nil
+}
+
+// execKeys runs script on every target at once, feeding it the keys.
+func execKeys(ctx context.Context, targets []*keyTarget, script string, keys []*cluster.UserKey) []*KeyResult {
+	var stdin strings.Builder
+	for _, key := range keys {
+		stdin.WriteString(key.AuthorizedKey() + "\n")
+	}
+
+	results := make([]*KeyResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = &KeyResult{InstanceName: target.instance, NodeName: target.node}
+			if err := execKey(ctx, target, script, stdin.String()); err != nil {
+				log.Printf("failed to update keys of instance %s: %v", target.instance, err)
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].InstanceName < results[j].InstanceName })
+	return results
+}
+
+func execKey(ctx context.Context, target *keyTarget, script string, stdin string) error {
+	if !target.exec {
+		return fmt.Errorf("worker on node %s can not run commands, upgrade it", target.node)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, keyExecTimeout)
+	defer cancel()
+	execReply, err := target.agentClient.Exec(ctx, &common.ExecRequest{
+		InstanceName: target.instance,
+		Command:      script,
+		Stdin:        []byte(stdin),
+	})
+	if err != nil {
+		return err
+	}
+	if execReply.ExitCode != 0 {
+		return fmt.Errorf("exit code %d: %s", execReply.ExitCode, strings.TrimSpace(string(execReply.Stderr)))
+	}
+	return nil
+}
+
+func (s *server) Keys(_ context.Context, _ *GetKeysRequest) (*GetKeysReply, error) {
+	keys := s.clusterServer.UserKeys()
+	getKeysReply := &GetKeysReply{Keys: make([]*Key, 0, len(keys))}
+	for _, key := range keys {
+		getKeysReply.Keys = append(getKeysReply.Keys, newKey(key))
+	}
+	return getKeysReply, nil
+}
+
+// AddKey registers a key and pushes it to running instances, later launches
+// getting it through cloud-init.
+func (s *server) AddKey(ctx context.Context, req *AddKeyRequest) (*AddKeyReply, error) {
+	key, err := cluster.NewUserKey(req.GetName(), req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+	if err = s.clusterServer.AddUserKey(key); err != nil {
+		return nil, err
+	}
+	log.Printf("added key %s: %s", key.Name, key.Fingerprint)
+
+	targets, err := s.keyTargets("")
+	if err != nil {
+		return nil, err
+	}
+	return &AddKeyReply{
+		Key:     newKey(key),
+		Results: execKeys(ctx, targets, pushKeysScript, []*cluster.UserKey{key}),
+	}, nil
+}
+
+// RemoveKey unregisters a key and revokes it on running instances.
+func (s *server) RemoveKey(ctx context.Context, req *RemoveKeyRequest) (*RemoveKeyReply, error) {
+	if req.GetName() == "" {
+		return nil, fmt.Errorf("key name is required")
+	}
+	key, err := s.clusterServer.RemoveUserKey(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("removed key %s: %s", key.Name, key.Fingerprint)
+
+	targets, err := s.keyTargets("")
+	if err != nil {
+		return nil, err
+	}
+	return &RemoveKeyReply{
+		Results: execKeys(ctx, targets, revokeKeysScript, []*cluster.UserKey{key}),
+	}, nil
+}
+
+// PushKeys pushes every registered key to running instances, e.g. ones that
+// were stopped while keys changed.
+func (s *server) PushKeys(ctx context.Context, req *PushKeysRequest) (*PushKeysReply, error) {
+	keys := s.clusterServer.UserKeys()
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys registered")
+	}
+	targets, err := s.keyTargets(req.GetInstanceName())
+	if err != nil {
+		return nil, err
+	}
+	return &PushKeysReply{Results: execKeys(ctx, targets, pushKeysScript, keys)}, nil
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/erayarslan/multiverse/cluster"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fakeKeys serves user keys, the rest of cluster.Server is left out.
+type fakeKeys struct {
+	cluster.Server
+	keys []*cluster.UserKey
+}
+
+func (c *fakeKeys) UserKeys() []*cluster.UserKey {
+	return c.keys
+}
+
+func TestCloudInit(t *testing.T) {
+	alice := &cluster.UserKey{Name: "alice", PublicKey: "ssh-ed25519 AAAAalice"}
+	bob := &cluster.UserKey{Name: "bob", PublicKey: "ssh-ed25519 AAAAbob"}
+
+	tests := []struct {
+		name     string
+		userData string
+		keys     []*cluster.UserKey
+		wantKeys []string
+		wantErr  bool
+	}{
+		{name: "no keys keeps user data", userData: "#!/bin/sh\necho hi\n"},
+		{name: "generated", keys: []*cluster.UserKey{alice, bob}, wantKeys: []string{alice.AuthorizedKey(), bob.AuthorizedKey()}},
+		{
+			name:     "merged into cloud-config",
+			userData: "#cloud-config\npackages:\n  - nginx\n",
+			keys:     []*cluster.UserKey{alice},
+			wantKeys: []string{alice.AuthorizedKey()},
+		},
+		{
+			name:     "appended to given keys",
+			userData: "#cloud-config\nssh_authorized_keys:\n  - ssh-ed25519 AAAAcarol carol\n",
+			keys:     []*cluster.UserKey{alice},
+			wantKeys: []string{"ssh-ed25519 AAAAcarol carol", alice.AuthorizedKey()},
+		},
+		{name: "script", userData: "#!/bin/sh\necho hi\n", keys: []*cluster.UserKey{alice}, wantErr: true},
+		{name: "not a mapping", userData: "#cloud-config\n- nginx\n", keys: []*cluster.UserKey{alice}, wantErr: true},
+		{
+			name:     "keys not a list",
+			userData: "#cloud-config\nssh_authorized_keys: carol\n",
+			keys:     []*cluster.UserKey{alice},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &server{clusterServer: &fakeKeys{keys: tt.keys}}
+
+			got, err := s.cloudInit(tt.userData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cloudInit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(tt.keys) == 0 {
+				if got != tt.userData {
+					t.Errorf("cloudInit() = %q, want %q", got, tt.userData)
+				}
+				return
+			}
+
+			body, ok := strings.CutPrefix(got, cloudConfigHeader+"\n")
+			if !ok {
+				t.Fatalf("cloudInit() = %q, want a cloud-config", got)
+			}
+			var config struct {
+				Packages          []string `yaml:"packages"`
+				SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys"`
+			}
+			if err = yaml.Unmarshal([]byte(body), &config); err != nil {
+				t.Fatalf("cloudInit() = %q, not yaml: %v", got, err)
+			}
+			if strings.Join(config.SSHAuthorizedKeys, "\n") != strings.Join(tt.wantKeys, "\n") {
+				t.Errorf("ssh_authorized_keys = %q, want %q", config.SSHAuthorizedKeys, tt.wantKeys)
+			}
+			if strings.Contains(tt.userData, "nginx") && len(config.Packages) != 1 {
+				t.Errorf("packages = %q, want the given ones kept", config.Packages)
+			}
+		})
+	}
+}
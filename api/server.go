@@ -5,10 +5,18 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/erayarslan/multiverse/agent"
 	"github.com/erayarslan/multiverse/cluster"
 	"github.com/erayarslan/multiverse/common"
+	"github.com/erayarslan/multiverse/history"
+	"github.com/erayarslan/multiverse/metrics"
+	"github.com/erayarslan/multiverse/multipass"
+	"github.com/erayarslan/multiverse/scheduler"
+	"github.com/erayarslan/multiverse/webhook"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -18,21 +26,35 @@ import (
 	"google.golang.org/grpc"
 )
 
+const defaultDrainTimeout = 2 * time.Minute
+
 type server struct {
 	UnimplementedRpcServer
 	clusterServer cluster.Server
+	scheduler     scheduler.Scheduler
+	notifier      webhook.Notifier
+	history       history.History
 	listener      net.Listener
 	grpcServer    *grpc.Server
+	closing       chan struct{}
 }
 
 type Server interface {
 	Serve() error
+	GracefulStop(timeout time.Duration)
 }
 
 func (s *server) Serve() error {
 	return s.grpcServer.Serve(s.listener)
 }
 
+// GracefulStop ends watches, notifies and closes shells and waits for pending
+// rpcs.
+func (s *server) GracefulStop(timeout time.Duration) {
+	close(s.closing)
+	common.GracefulStop(s.grpcServer, timeout)
+}
+
 func (s *server) Info(ctx context.Context, _ *GetInfoRequest) (*GetInfoReply, error) {
 	getInfoReply := &GetInfoReply{
 		Instances: make([]*GetInfoInstance, 0),
@@ -83,10 +105,17 @@ func (s *server) Nodes(_ context.Context, _ *GetNodesRequest) (*GetNodesReply, e
 
 	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
 		getNodesReply.Nodes = append(getNodesReply.Nodes, &Node{
-			Name:     workerInfo.NodeName,
-			LastSync: workerInfo.LastSync,
-			Ipv4:     []string{workerInfo.IPPort},
-			Resource: workerInfo.State.Resource,
+			Name:         workerInfo.NodeName,
+			LastSync:     workerInfo.LastSync,
+			Ipv4:         []string{workerInfo.IPPort},
+			Resource:     workerInfo.State.Resource,
+			Labels:       workerInfo.Labels,
+			Taints:       workerInfo.Taints,
+			Cordoned:     workerInfo.Cordoned,
+			Ready:        workerInfo.Ready,
+			Version:      workerInfo.Version.Version,
+			Protocol:     uint32(workerInfo.Version.Protocol),
+			Capabilities: workerInfo.Version.Capabilities,
 		})
 		return true
 	})
@@ -95,33 +124,221 @@ func (s *server) Nodes(_ context.Context, _ *GetNodesRequest) (*GetNodesReply, e
 }
 
 func (s *server) Launch(ctx context.Context, req *common.LaunchRequest) (*common.LaunchReply, error) {
-	// todo: detect best worker due to resource
+	workerInfo, err := s.scheduler.Schedule(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.CloudInitUserData, err = s.cloudInit(req.CloudInitUserData); err != nil {
+		return nil, err
+	}
+	// older workers drop user data they do not know
+	if req.CloudInitUserData != "" && !workerInfo.Version.Has(common.CapabilityCloudInit) {
+		return nil, fmt.Errorf("worker on node %s is too old for cloud-init user data, upgrade it", workerInfo.NodeName)
+	}
+	log.Printf("launching instance %s on node: %s", req.InstanceName, workerInfo.NodeName)
+	s.clusterServer.Publish(cluster.NewEvent(common.EventType_LAUNCH_STARTED, workerInfo.NodeName, req.InstanceName))
+
+	launchReply, err := workerInfo.AgentClient.Launch(ctx, req)
+
+	finished := cluster.NewEvent(common.EventType_LAUNCH_FINISHED, workerInfo.NodeName, req.InstanceName)
+	if err != nil {
+		finished.Message = err.Error()
+	}
+	s.clusterServer.Publish(finished)
+
+	return launchReply, err
+}
+
+func (s *server) Watch(req *WatchRequest, stream grpc.ServerStreamingServer[common.Event]) error {
+	events, unsubscribe := s.clusterServer.Subscribe()
+	defer unsubscribe()
+
+	types := make(map[common.EventType]bool, len(req.Types))
+	for _, t := range req.Types {
+		types[t] = true
+	}
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if len(types) > 0 && !types[event.Type] {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.closing:
+			return nil
+		}
+	}
+}
+
+func (s *server) Taint(_ context.Context, req *TaintRequest) (*TaintReply, error) {
+	if req.GetNodeName() == "" || req.GetTaint() == nil {
+		return nil, fmt.Errorf("node name and taint are required")
+	}
+	if err := common.ValidateTaint(req.Taint); err != nil {
+		return nil, err
+	}
+	if err := s.clusterServer.Taint(req.NodeName, req.Taint); err != nil {
+		return nil, err
+	}
+	return &TaintReply{}, nil
+}
+
+func (s *server) Untaint(_ context.Context, req *UntaintRequest) (*UntaintReply, error) {
+	if err := s.clusterServer.Untaint(req.NodeName, req.Key); err != nil {
+		return nil, err
+	}
+	return &UntaintReply{}, nil
+}
+
+func (s *server) Cordon(_ context.Context, req *CordonRequest) (*CordonReply, error) {
+	if req.GetNodeName() == "" {
+		return nil, fmt.Errorf("node name is required")
+	}
+	if err := s.clusterServer.Cordon(req.NodeName, true); err != nil {
+		return nil, err
+	}
+	return &CordonReply{}, nil
+}
+
+func (s *server) Uncordon(_ context.Context, req *UncordonRequest) (*UncordonReply, error) {
+	if req.GetNodeName() == "" {
+		return nil, fmt.Errorf("node name is required")
+	}
+	if err := s.clusterServer.Cordon(req.NodeName, false); err != nil {
+		return nil, err
+	}
+	return &UncordonReply{}, nil
+}
+
+func isDrained(state string) bool {
+	switch state {
+	case multipass.InstanceStatus_STOPPED.ToString(),
+		multipass.InstanceStatus_SUSPENDED.ToString(),
+		multipass.InstanceStatus_DELETED.ToString():
+		return true
+	default:
+		return false
+	}
+}
+
+func drainInstance(ctx context.Context, agentClient agent.Client, instanceName string, req *DrainRequest) error {
+	timeout := req.GetTimeout().AsDuration()
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	switch req.Action {
+	case DrainAction_SUSPEND:
+		_, err = agentClient.Suspend(timeoutCtx, &common.SuspendRequest{InstanceName: instanceName})
+	case DrainAction_STOP:
+		_, err = agentClient.Stop(timeoutCtx, &common.StopRequest{InstanceName: instanceName})
+		if err != nil && req.Force {
+			log.Printf("failed to stop instance %s, forcing: %v", instanceName, err)
+			forceCtx, forceCancel := context.WithTimeout(ctx, timeout)
+			defer forceCancel()
+			_, err = agentClient.Stop(forceCtx, &common.StopRequest{InstanceName: instanceName, Force: true})
+		}
+	}
+	return err
+}
+
+func (s *server) Drain(ctx context.Context, req *DrainRequest) (*DrainReply, error) {
+	if req.GetNodeName() == "" {
+		return nil, fmt.Errorf("node name is required")
+	}
+
+	// cordoning an unknown node would keep a spec for a mistyped name
+	known := false
+	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
+		known = workerInfo.NodeName == req.NodeName
+		return !known
+	})
+	if !known {
+		return nil, fmt.Errorf("node not found: %s", req.NodeName)
+	}
+
+	// instances are listed after cordoning so no launch slips in between
+	if err := s.clusterServer.Cordon(req.NodeName, true); err != nil {
+		return nil, err
+	}
+
 	var agentClient agent.Client
+	var instances []*agent.Instance
 	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
-		agentClient = workerInfo.AgentClient
-		return false
+		if workerInfo.NodeName == req.NodeName {
+			agentClient = workerInfo.AgentClient
+			instances = workerInfo.State.Instances
+			return false
+		}
+		return true
 	})
 	if agentClient == nil {
-		return nil, fmt.Errorf("agent client not found")
+		return nil, fmt.Errorf("node not found: %s", req.NodeName)
 	}
-	return agentClient.Launch(ctx, req)
-}
 
-func (s *server) Shell(stream grpc.BidiStreamingServer[common.ShellRequest, common.ShellReply]) error {
-	md, ok := metadata.FromIncomingContext(stream.Context())
-	if !ok {
-		return fmt.Errorf("metadata not found in context")
+	drainReply := &DrainReply{
+		Results: make([]*DrainResult, 0, len(instances)),
 	}
 
-	instanceName := md.Get("instanceName")
-	if len(instanceName) == 0 {
-		return fmt.Errorf("instance name not found in context")
+	for _, instance := range instances {
+		if isDrained(instance.State) {
+			continue
+		}
+
+		result := &DrainResult{InstanceName: instance.Name}
+		if err := drainInstance(ctx, agentClient, instance.Name, req); err != nil {
+			log.Printf("failed to drain instance %s: %v", instance.Name, err)
+			result.Error = err.Error()
+		}
+		drainReply.Results = append(drainReply.Results, result)
+	}
+
+	return drainReply, nil
+}
+
+func (s *server) WebhookDeliveries(_ context.Context, _ *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesReply, error) {
+	return &GetWebhookDeliveriesReply{
+		Deliveries: s.notifier.Deliveries(),
+	}, nil
+}
+
+func (s *server) History(_ context.Context, req *GetHistoryRequest) (*common.History, error) {
+	var start, end time.Time
+	if req.Start != nil {
+		start = req.Start.AsTime()
+	}
+	if req.End != nil {
+		end = req.End.AsTime()
 	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, fmt.Errorf("end %s is before start %s", end, start)
+	}
+
+	return s.history.Query(start, end, req.NodeName, req.InstanceName), nil
+}
 
+// agentClientOn finds the agent running an instance, on nodeName unless it is
+// empty.
+func (s *server) agentClientOn(instanceName string, nodeName string) (agent.Client, error) {
 	var agentClient agent.Client
 	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
+		if nodeName != "" && workerInfo.NodeName != nodeName {
+			return true
+		}
 		for _, instance := range workerInfo.State.Instances {
-			if instance.Name == instanceName[0] {
+			if instance.Name == instanceName {
 				agentClient = workerInfo.AgentClient
 				return false
 			}
@@ -129,15 +346,79 @@ func (s *server) Shell(stream grpc.BidiStreamingServer[common.ShellRequest, comm
 		return true
 	})
 	if agentClient == nil {
-		return fmt.Errorf("agent client not found with instance name: %s", instanceName[0])
+		if nodeName != "" {
+			return nil, fmt.Errorf("agent client not found with instance name: %s on node: %s", instanceName, nodeName)
+		}
+		return nil, fmt.Errorf("agent client not found with instance name: %s", instanceName)
+	}
+	return agentClient, nil
+}
+
+// qualifiedAgentClientOf finds the agent of name, <instance> or
+// <instance>.<node> when names repeat across nodes, returning the bare
+// instance name the agent knows.
+func (s *server) qualifiedAgentClientOf(name string) (agent.Client, string, error) {
+	instanceName, nodeName, _ := strings.Cut(name, ".")
+	agentClient, err := s.agentClientOn(instanceName, nodeName)
+	return agentClient, instanceName, err
+}
+
+func (s *server) Stop(ctx context.Context, req *common.StopRequest) (*common.StopReply, error) {
+	if req.GetInstanceName() == "" {
+		return nil, fmt.Errorf("instance name is required")
+	}
+
+	agentClient, instanceName, err := s.qualifiedAgentClientOf(req.InstanceName)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("stopping instance %s", req.InstanceName)
+	return agentClient.Stop(ctx, &common.StopRequest{InstanceName: instanceName, Force: req.Force})
+}
+
+func (s *server) Shell(stream grpc.BidiStreamingServer[common.ShellRequest, common.ShellReply]) error {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	if !ok {
+		return fmt.Errorf("metadata not found in context")
 	}
 
-	ctx := metadata.NewOutgoingContext(context.Background(), md.Copy())
+	instanceName := md.Get("instanceName")
+	if len(instanceName) == 0 {
+		return fmt.Errorf("instance name not found in context")
+	}
+
+	agentClient, bareName, err := s.qualifiedAgentClientOf(instanceName[0])
+	if err != nil {
+		return err
+	}
+
+	md = md.Copy()
+	md.Set("instanceName", bareName)
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), md))
+	defer cancel()
 	agentStream, err := agentClient.Shell(ctx)
 	if err != nil {
 		return err
 	}
 
+	metrics.ShellSessions.WithLabelValues("master").Inc()
+	defer metrics.ShellSessions.WithLabelValues("master").Dec()
+
+	var sendMu sync.Mutex
+	go func() {
+		select {
+		case <-s.closing:
+			sendMu.Lock()
+			if err := stream.Send(common.ShutdownNotice()); err != nil {
+				log.Printf("failed to notify shell: %v", err)
+			}
+			sendMu.Unlock()
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	go func() {
 		err := common.ListenBidiServer(stream, func(req *common.ShellRequest) error {
 			return agentStream.Send(&common.ShellRequest{
@@ -155,26 +436,144 @@ func (s *server) Shell(stream grpc.BidiStreamingServer[common.ShellRequest, comm
 		}
 	}()
 
-	return common.ListenBidiClient(agentStream, func(res *common.ShellReply) error {
+	err = common.ListenBidiClient(agentStream, func(res *common.ShellReply) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
 		return stream.Send(&common.ShellReply{
 			OutBuffer: res.GetOutBuffer(),
 			ErrBuffer: res.GetErrBuffer(),
 		})
 	})
+	if status.Code(err) == codes.Canceled {
+		return nil
+	}
+	return err
 }
 
-func NewServer(addr string, clusterServer cluster.Server) (Server, error) {
+func (s *server) SshInfo(ctx context.Context, req *common.SSHInfoRequest) (*common.SSHInfoReply, error) {
+	agentClient, instanceName, err := s.qualifiedAgentClientOf(req.GetInstanceName())
+	if err != nil {
+		return nil, err
+	}
+	return agentClient.SSHInfo(ctx, &common.SSHInfoRequest{InstanceName: instanceName})
+}
+
+// Forward relays a tcp connection to an instance through the agent of its
+// node, ending it when master shuts down.
+func (s *server) Forward(stream grpc.BidiStreamingServer[common.ForwardFrame, common.ForwardFrame]) error {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	if !ok {
+		return fmt.Errorf("metadata not found in context")
+	}
+
+	instanceName := md.Get("instanceName")
+	if len(instanceName) == 0 {
+		return fmt.Errorf("instance name not found in context")
+	}
+
+	agentClient, bareName, err := s.qualifiedAgentClientOf(instanceName[0])
+	if err != nil {
+		return err
+	}
+
+	md = md.Copy()
+	md.Set("instanceName", bareName)
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(stream.Context(), md))
+	defer cancel()
+	agentStream, err := agentClient.Forward(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	go func() {
+		err := common.ListenBidiServer(stream, agentStream.Send)
+		if err == nil {
+			err = agentStream.CloseSend()
+		}
+		if err != nil && status.Code(err) != codes.Canceled {
+			log.Printf("failed to listen forward stream: %v", err)
+		}
+	}()
+
+	err = common.ListenBidiClient(agentStream, stream.Send)
+	if status.Code(err) == codes.Canceled {
+		return nil
+	}
+	return err
+}
+
+func (s *server) Version(_ context.Context, _ *GetVersionRequest) (*GetVersionReply, error) {
+	return &GetVersionReply{
+		Version:      common.Version,
+		Protocol:     common.ProtocolVersion,
+		MinProtocol:  common.MinProtocolVersion,
+		Capabilities: common.Capabilities,
+		Leader:       s.clusterServer.IsLeader(),
+	}, nil
+}
+
+// checkLeader refuses calls on masters following the leader, except version
+// which clients ask every master to find the leader.
+func (s *server) checkLeader(method string) error {
+	if method == Rpc_Version_FullMethodName || s.clusterServer.IsLeader() {
+		return nil
+	}
+	return status.Error(codes.Unavailable, notLeaderMessage)
+}
+
+func (s *server) leaderUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := s.checkLeader(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *server) leaderStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := s.checkLeader(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func NewServer(addr string, auth *ServerAuth, clusterServer cluster.Server, scheduler scheduler.Scheduler,
+	notifier webhook.Notifier, history history.History,
+) (Server, error) {
+	authOpts, err := auth.serverOptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api tls: %w", err)
+	}
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
-	var opts []grpc.ServerOption
-	grpcServer := grpc.NewServer(opts...)
 	server := &server{
 		clusterServer: clusterServer,
+		scheduler:     scheduler,
+		notifier:      notifier,
+		history:       history,
 		listener:      lis,
-		grpcServer:    grpcServer,
+		closing:       make(chan struct{}),
 	}
-	RegisterRpcServer(grpcServer, server)
+	opts := append(metrics.ServerOptions("api"), authOpts...)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(auth.tokenUnaryServerInterceptor, versionUnaryServerInterceptor,
+			server.leaderUnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(auth.tokenStreamServerInterceptor, versionStreamServerInterceptor,
+			server.leaderStreamServerInterceptor),
+	)
+	server.grpcServer = grpc.NewServer(opts...)
+	RegisterRpcServer(server.grpcServer, server)
 	return server, nil
 }
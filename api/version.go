@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/erayarslan/multiverse/common"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// checkClientVersion refuses clients older than the minimum protocol, clients
+// advertising nothing, like the gateway or grpcurl, are served as they are.
+func checkClientVersion(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	version := common.PeerVersionFromMetadata(md)
+	if version.Protocol == 0 {
+		return nil
+	}
+	if err := version.Compatible(); err != nil {
+		return status.Errorf(codes.FailedPrecondition, "client %v", err)
+	}
+	return nil
+}
+
+func versionUnaryServerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := grpc.SetHeader(ctx, common.VersionMetadata()); err != nil {
+		return nil, err
+	}
+	if err := checkClientVersion(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func versionStreamServerInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := ss.SetHeader(common.VersionMetadata()); err != nil {
+		return err
+	}
+	if err := checkClientVersion(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// versionUnaryClientInterceptor advertises the client and names the master
+// version when master is too old to know a method.
+func versionUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+) error {
+	var header metadata.MD
+	ctx = metadata.NewOutgoingContext(ctx, outgoingVersion(ctx))
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+	if status.Code(err) == codes.Unimplemented {
+		master := common.PeerVersionFromMetadata(header)
+		return fmt.Errorf("master %s does not support %s, upgrade master: %w", master, method, err)
+	}
+	return err
+}
+
+func versionStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+	streamer grpc.Streamer, opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	ctx = metadata.NewOutgoingContext(ctx, outgoingVersion(ctx))
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+func outgoingVersion(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.Join(md, common.VersionMetadata())
+}
@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/erayarslan/multiverse/api"
+	"github.com/erayarslan/multiverse/config"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+const defaultAPIServerAddr = "localhost:1338"
+
+// globalFlags are bound on every command and read from the environment.
+var globalFlags = []string{"api-server-addr", "context", "client-config"}
+
+type env struct {
+	apiClient        api.Client
+	stdout           io.Writer
+	stderr           io.Writer
+	apiServerAddr    string
+	context          string
+	clientConfig     string
+	apiServerAddrSet bool
+}
+
+func (e *env) bind(fs *flag.FlagSet) {
+	fs.StringVar(&e.apiServerAddr, "api-server-addr", defaultAPIServerAddr,
+		"api server addr to connect, overrides the context")
+	fs.StringVar(&e.context, "context", "", "client context to use instead of the current context")
+	fs.StringVar(&e.clientConfig, "client-config", config.DefaultClientConfigPath(), "client config file holding contexts")
+}
+
+func (e *env) loadClientConfig() (*config.ClientConfig, error) {
+	return config.LoadClientConfig(e.clientConfig)
+}
+
+func (e *env) client() (api.Client, error) {
+	if e.apiClient != nil {
+		return e.apiClient, nil
+	}
+
+	clientConfig, err := e.loadClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	current, err := clientConfig.Current(e.context)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := e.apiServerAddr
+	var creds *api.Credentials
+	if current != nil {
+		if !e.apiServerAddrSet && current.APIServerAddr != "" {
+			addr = current.APIServerAddr
+		}
+		creds = &api.Credentials{
+			Token:    current.Token,
+			CAFile:   current.CAFile,
+			CertFile: current.CertFile,
+			KeyFile:  current.KeyFile,
+		}
+	}
+
+	apiClient, err := api.NewClient(addr, creds)
+	if err != nil {
+		return nil, err
+	}
+	e.apiClient = apiClient
+	return e.apiClient, nil
+}
+
+func (e *env) close() {
+	if e.apiClient != nil {
+		_ = e.apiClient.Close()
+	}
+}
+
+type command struct {
+	flags    *flag.FlagSet
+	run      func(ctx context.Context, e *env, args []string) error
+	complete func(ctx context.Context, e *env) []string
+	name     string
+	args     string
+	short    string
+	aliases  []string
+	children []*command
+}
+
+func (c *command) names() []string {
+	return append([]string{c.name}, c.aliases...)
+}
+
+func (c *command) child(name string) *command {
+	for _, child := range c.children {
+		for _, n := range child.names() {
+			if n == name {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+func (c *command) usage(w io.Writer, path []string) {
+	_, _ = fmt.Fprintf(w, "Usage: %s", strings.Join(path, " "))
+	if len(c.children) > 0 {
+		_, _ = fmt.Fprint(w, " <command>")
+	}
+	if c.args != "" {
+		_, _ = fmt.Fprintf(w, " %s", c.args)
+	}
+	_, _ = fmt.Fprint(w, " [flags]\n")
+	if c.short != "" {
+		_, _ = fmt.Fprintf(w, "\n%s\n", c.short)
+	}
+
+	if len(c.children) > 0 {
+		_, _ = fmt.Fprint(w, "\nCommands:\n")
+		for _, child := range c.children {
+			_, _ = fmt.Fprintf(w, "  %-12s %s\n", child.name, child.short)
+		}
+	}
+
+	if c.flags != nil {
+		_, _ = fmt.Fprint(w, "\nFlags:\n")
+		c.flags.SetOutput(w)
+		c.flags.PrintDefaults()
+	}
+}
+
+// parse lets flags and positional arguments interleave, unlike flag.Parse
+// which stops at the first positional argument.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func (c *command) execute(ctx context.Context, e *env, path []string, args []string) error {
+	if len(c.children) > 0 {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+			c.usage(e.stdout, path)
+			return nil
+		}
+		child := c.child(args[0])
+		if child == nil {
+			return usageErrorf("unknown command %q for %q", args[0], strings.Join(path, " "))
+		}
+		return child.execute(ctx, e, append(path, child.name), args[1:])
+	}
+
+	fs := c.flags
+	if fs == nil {
+		fs = flag.NewFlagSet(c.name, flag.ContinueOnError)
+	}
+	e.bind(fs)
+	fs.SetOutput(io.Discard)
+	if err := config.ApplyEnv(fs, globalFlags...); err != nil {
+		return &usageError{err: err}
+	}
+
+	positional, err := parse(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		c.flags = fs
+		c.usage(e.stdout, path)
+		return nil
+	}
+	if err != nil {
+		c.flags = fs
+		c.usage(e.stderr, path)
+		return &usageError{err: err}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "api-server-addr" {
+			e.apiServerAddrSet = true
+		}
+	})
+
+	return c.run(ctx, e, positional)
+}
+
+func exactArgs(args []string, names ...string) error {
+	if len(names) == 0 && len(args) > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	if len(args) != len(names) {
+		return usageErrorf("expected %d argument(s) %s, got %d", len(names), strings.Join(names, " "), len(args))
+	}
+	return nil
+}
+
+func newRoot() *command {
+	root := &command{
+		name:  "multiverse",
+		short: "Manage a multiverse cluster.",
+		children: []*command{
+			nodesCommand(),
+			instancesCommand(),
+			eventsCommand(),
+			webhooksCommand(),
+			topCommand(),
+			configCommand(),
+			versionCommand(),
+			exportCommand(),
+			keysCommand(),
+		},
+	}
+	root.children = append(root.children, completionCommand())
+	sort.SliceStable(root.children, func(i, j int) bool {
+		return root.children[i].name < root.children[j].name
+	})
+	return root
+}
+
+// Run executes the command tree against args and returns the process exit code.
+func Run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	e := &env{
+		stdout: stdout,
+		stderr: stderr,
+	}
+	defer e.close()
+
+	root := newRoot()
+	if len(args) > 0 && args[0] == completeCommandName {
+		complete(ctx, e, root, args[1:])
+		return exitOK
+	}
+
+	err := root.execute(ctx, e, []string{root.name}, args)
+	if err == nil {
+		return exitOK
+	}
+
+	_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+	return exitError
+}
+
+// IsCommand reports whether args select a subcommand rather than a role flag.
+func IsCommand(args []string) bool {
+	return len(args) > 0 && !strings.HasPrefix(args[0], "-")
+}
+
+func Main(args []string) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	return Run(ctx, args, os.Stdout, os.Stderr)
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/erayarslan/multiverse/config"
+)
+
+// completeCommandName is the hidden command the completion scripts call
+// with the words typed so far, the last one being the word to complete.
+const completeCommandName = "__complete"
+
+var completionScripts = map[string]string{
+	"bash": `_multiverse() {
+  local IFS=$'\n'
+  COMPREPLY=($(multiverse __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _multiverse multiverse
+`,
+	"zsh": `#compdef multiverse
+_multiverse() {
+  local -a completions
+  completions=(${(f)"$(multiverse __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+  compadd -a completions
+}
+compdef _multiverse multiverse
+`,
+	"fish": `complete -c multiverse -f -a '(multiverse __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`,
+}
+
+func completionCommand() *command {
+	return &command{
+		name:  "completion",
+		args:  "<bash|zsh|fish>",
+		short: "Print shell completion script.",
+		complete: func(_ context.Context, _ *env) []string {
+			return []string{"bash", "fish", "zsh"}
+		},
+		run: func(_ context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<bash|zsh|fish>"); err != nil {
+				return err
+			}
+			script, ok := completionScripts[args[0]]
+			if !ok {
+				return usageErrorf("unsupported shell: %s", args[0])
+			}
+			_, err := fmt.Fprint(e.stdout, script)
+			return err
+		},
+	}
+}
+
+func complete(ctx context.Context, e *env, root *command, words []string) {
+	current := ""
+	if len(words) > 0 {
+		current, words = words[len(words)-1], words[:len(words)-1]
+	}
+
+	c := root
+	positional := 0
+	for _, word := range words {
+		if child := c.child(word); child != nil && len(c.children) > 0 {
+			c = child
+			continue
+		}
+		if !strings.HasPrefix(word, "-") {
+			positional++
+		}
+	}
+
+	globals := flag.NewFlagSet("globals", flag.ContinueOnError)
+	e.bind(globals)
+	_ = config.ApplyEnv(globals, globalFlags...)
+
+	candidates := make([]string, 0)
+	switch {
+	case strings.HasPrefix(current, "-"):
+		addFlag := func(f *flag.Flag) {
+			candidates = append(candidates, "-"+f.Name)
+		}
+		if c.flags != nil {
+			c.flags.VisitAll(addFlag)
+		}
+		globals.VisitAll(addFlag)
+	case len(c.children) > 0:
+		for _, child := range c.children {
+			candidates = append(candidates, child.name)
+		}
+	case c.complete != nil && positional == 0:
+		candidates = c.complete(ctx, e)
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			_, _ = fmt.Fprintln(e.stdout, candidate)
+		}
+	}
+}
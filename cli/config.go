@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/erayarslan/multiverse/config"
+)
+
+func contextNames(_ context.Context, e *env) []string {
+	clientConfig, err := e.loadClientConfig()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(clientConfig.Contexts))
+	for _, c := range clientConfig.Contexts {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func configCurrentContextCommand() *command {
+	return &command{
+		name:  "current-context",
+		short: "Print the current context.",
+		run: func(_ context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			clientConfig, err := e.loadClientConfig()
+			if err != nil {
+				return err
+			}
+			if clientConfig.CurrentContext == "" {
+				return fmt.Errorf("current context is not set")
+			}
+			_, err = fmt.Fprintln(e.stdout, clientConfig.CurrentContext)
+			return err
+		},
+	}
+}
+
+func configGetContextsCommand() *command {
+	return &command{
+		name:  "get-contexts",
+		short: "List contexts.",
+		run: func(_ context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			clientConfig, err := e.loadClientConfig()
+			if err != nil {
+				return err
+			}
+
+			t := &table{columns: []column{{name: "Current"}, {name: "Name"}, {name: "Api Server Addr"}, {name: "Auth"}}}
+			for _, c := range clientConfig.Contexts {
+				current := ""
+				if c.Name == clientConfig.CurrentContext {
+					current = "*"
+				}
+				auth := make([]string, 0, 2)
+				if c.Token != "" {
+					auth = append(auth, "token")
+				}
+				if c.CAFile != "" || c.CertFile != "" {
+					auth = append(auth, "tls")
+				}
+				t.append(current, c.Name, c.APIServerAddr, missingIfEmpty(strings.Join(auth, ",")))
+			}
+			return t.write(e.stdout, false)
+		},
+	}
+}
+
+func configUseContextCommand() *command {
+	return &command{
+		name:     "use-context",
+		args:     "<name>",
+		short:    "Set the current context.",
+		complete: contextNames,
+		run: func(_ context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>"); err != nil {
+				return err
+			}
+			clientConfig, err := e.loadClientConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := clientConfig.Context(args[0]); !ok {
+				return fmt.Errorf("context not found: %s", args[0])
+			}
+			clientConfig.CurrentContext = args[0]
+			if err = clientConfig.Save(e.clientConfig); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "switched to context %s\n", args[0])
+			return err
+		},
+	}
+}
+
+func configSetContextCommand() *command {
+	var server, token, caFile, certFile, keyFile string
+	fs := flag.NewFlagSet("set-context", flag.ContinueOnError)
+	fs.StringVar(&server, "server", "", "api server addr of the context")
+	fs.StringVar(&token, "token", "", "bearer token sent to the api server")
+	fs.StringVar(&caFile, "ca-file", "", "ca certificate to verify the api server")
+	fs.StringVar(&certFile, "cert-file", "", "client certificate for mutual tls")
+	fs.StringVar(&keyFile, "key-file", "", "client key for mutual tls")
+
+	return &command{
+		name:     "set-context",
+		args:     "<name>",
+		short:    "Create or update a context, only given flags are changed.",
+		flags:    fs,
+		complete: contextNames,
+		run: func(_ context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>"); err != nil {
+				return err
+			}
+			clientConfig, err := e.loadClientConfig()
+			if err != nil {
+				return err
+			}
+
+			c, ok := clientConfig.Context(args[0])
+			if !ok {
+				c = &config.ClientContext{Name: args[0], APIServerAddr: defaultAPIServerAddr}
+			}
+			fs.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "server":
+					c.APIServerAddr = server
+				case "token":
+					c.Token = token
+				case "ca-file":
+					c.CAFile = caFile
+				case "cert-file":
+					c.CertFile = certFile
+				case "key-file":
+					c.KeyFile = keyFile
+				}
+			})
+			if (c.CertFile == "") != (c.KeyFile == "") {
+				return usageErrorf("cert-file and key-file must be set together")
+			}
+
+			clientConfig.SetContext(c)
+			if clientConfig.CurrentContext == "" {
+				clientConfig.CurrentContext = c.Name
+			}
+			if err = clientConfig.Save(e.clientConfig); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "context %s saved\n", c.Name)
+			return err
+		},
+	}
+}
+
+func configDeleteContextCommand() *command {
+	return &command{
+		name:     "delete-context",
+		args:     "<name>",
+		short:    "Delete a context.",
+		complete: contextNames,
+		run: func(_ context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>"); err != nil {
+				return err
+			}
+			clientConfig, err := e.loadClientConfig()
+			if err != nil {
+				return err
+			}
+			if !clientConfig.DeleteContext(args[0]) {
+				return fmt.Errorf("context not found: %s", args[0])
+			}
+			if err = clientConfig.Save(e.clientConfig); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "context %s deleted\n", args[0])
+			return err
+		},
+	}
+}
+
+func configCommand() *command {
+	return &command{
+		name:  "config",
+		short: "Manage client contexts.",
+		children: []*command{
+			configCurrentContextCommand(),
+			configGetContextsCommand(),
+			configUseContextCommand(),
+			configSetContextCommand(),
+			configDeleteContextCommand(),
+		},
+	}
+}
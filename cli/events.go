@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/erayarslan/multiverse/common"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func formatEvent(event *common.Event) string {
+	line := fmt.Sprintf("%s\t%s\tnode=%s", event.Time.AsTime().Format(timeFormat), event.Type, event.NodeName)
+	if event.InstanceName != "" {
+		line += fmt.Sprintf("\tinstance=%s", event.InstanceName)
+	}
+	if event.OldValue != "" || event.NewValue != "" {
+		line += fmt.Sprintf("\t%s -> %s", event.OldValue, event.NewValue)
+	}
+	if event.Message != "" {
+		line += fmt.Sprintf("\t%s", event.Message)
+	}
+	return line
+}
+
+func eventsWatchCommand() *command {
+	var out output
+	var types string
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	out.bind(fs)
+	fs.StringVar(&types, "types", "", "event types to watch as NODE_JOINED,INSTANCE_STATE_CHANGED")
+
+	return &command{
+		name:  "watch",
+		short: "Watch cluster events, json output is one event per line.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if out.format != formatTable && out.format != formatJSON {
+				return usageErrorf("unsupported output format for watch: %s", out.format)
+			}
+
+			eventTypes := make([]common.EventType, 0)
+			for _, name := range splitList(types) {
+				t, ok := common.EventType_value[strings.ToUpper(name)]
+				if !ok {
+					return usageErrorf("unknown event type: %s", name)
+				}
+				eventTypes = append(eventTypes, common.EventType(t))
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			err = apiClient.Watch(ctx, eventTypes, func(event *common.Event) error {
+				line := formatEvent(event)
+				if out.format == formatJSON {
+					bytes, err := protojson.Marshal(event)
+					if err != nil {
+						return err
+					}
+					line = string(bytes)
+				}
+				_, err := fmt.Fprintln(e.stdout, line)
+				return err
+			})
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		},
+	}
+}
+
+func eventsCommand() *command {
+	return &command{
+		name:     "events",
+		aliases:  []string{"event"},
+		short:    "Observe cluster events.",
+		children: []*command{eventsWatchCommand()},
+	}
+}
+
+func webhooksDeliveriesCommand() *command {
+	var out output
+	fs := flag.NewFlagSet("deliveries", flag.ContinueOnError)
+	out.bind(fs)
+
+	return &command{
+		name:  "deliveries",
+		short: "List recent webhook deliveries.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			getWebhookDeliveriesReply, err := apiClient.WebhookDeliveries(ctx)
+			if err != nil {
+				return err
+			}
+
+			t := &table{columns: []column{
+				{name: "Time"}, {name: "Sink"}, {name: "Event"}, {name: "Attempt"}, {name: "Status"}, {name: "Error"},
+			}}
+			for _, d := range getWebhookDeliveriesReply.Deliveries {
+				status := fmt.Sprintf("%d", d.StatusCode)
+				if d.DeadLettered {
+					status += " (dead lettered)"
+				}
+				t.append(
+					d.Time.AsTime().Format(timeFormat),
+					d.Sink,
+					d.Event.GetType().String(),
+					fmt.Sprintf("%d", d.Attempt),
+					status,
+					d.Error,
+				)
+			}
+
+			return out.print(e.stdout, getWebhookDeliveriesReply, t)
+		},
+	}
+}
+
+func webhooksCommand() *command {
+	return &command{
+		name:     "webhooks",
+		aliases:  []string{"webhook"},
+		short:    "Inspect webhook sinks.",
+		children: []*command{webhooksDeliveriesCommand()},
+	}
+}
@@ -0,0 +1,374 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/erayarslan/multiverse/common"
+	"github.com/erayarslan/multiverse/config"
+)
+
+const (
+	exportINI  = "ini"
+	exportJSON = "json"
+)
+
+var groupNameRegexp = regexp.MustCompile(`[^a-z0-9_]+`)
+
+type exportHost struct {
+	labels  map[string]string
+	name    string
+	node    string
+	state   string
+	image   string
+	user    string
+	keyFile string
+	ipv4    []string
+}
+
+// host names the instance on its node, which master resolves even when
+// instance names repeat across nodes.
+func (h *exportHost) host() string {
+	return h.name + "." + h.node
+}
+
+func defaultKeyDir() string {
+	return filepath.Join(filepath.Dir(config.DefaultClientConfigPath()), "keys")
+}
+
+// globalArgs repeats the global flags set on this run, so a generated
+// ProxyCommand reaches the same master.
+func (e *env) globalArgs() []string {
+	args := make([]string, 0)
+	if e.apiServerAddrSet {
+		args = append(args, "-api-server-addr", e.apiServerAddr)
+	}
+	if e.context != "" {
+		args = append(args, "-context", e.context)
+	}
+	if e.clientConfig != config.DefaultClientConfigPath() {
+		args = append(args, "-client-config", e.clientConfig)
+	}
+	return args
+}
+
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// proxyCommand tunnels ssh to the instance named by %h through master.
+func (e *env) proxyCommand() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	args := append([]string{exe, "instances", "proxy"}, e.globalArgs()...)
+	args = append(args, "%h")
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// collectHosts lists instances with the ssh user and key of their node, the
+// key being written to keyDir since multipass uses one key per node.
+func collectHosts(ctx context.Context, e *env, keyDir string) ([]*exportHost, error) {
+	apiClient, err := e.client()
+	if err != nil {
+		return nil, err
+	}
+	getInstancesReply, err := apiClient.Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	getNodesReply, err := apiClient.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]map[string]string, len(getNodesReply.Nodes))
+	for _, n := range getNodesReply.Nodes {
+		labels[n.Name] = n.Labels
+	}
+
+	instances := getInstancesReply.Instances
+	sort.SliceStable(instances, func(i, j int) bool { return instances[i].Instance.Name < instances[j].Instance.Name })
+
+	// only running instances answer ssh info
+	keys := make(map[string]*common.SSHInfoReply)
+	for _, i := range instances {
+		if _, ok := keys[i.NodeName]; ok || !strings.EqualFold(i.Instance.State, "running") {
+			continue
+		}
+		info, err := apiClient.SSHInfo(ctx, i.Instance.Name+"."+i.NodeName)
+		if err != nil {
+			_, _ = fmt.Fprintf(e.stderr, "warning: no ssh key of node %s: %v\n", i.NodeName, err)
+			continue
+		}
+		if err = writeKey(keyDir, i.NodeName, info.PrivateKey); err != nil {
+			return nil, err
+		}
+		keys[i.NodeName] = info
+	}
+
+	hosts := make([]*exportHost, 0, len(instances))
+	for _, i := range instances {
+		host := &exportHost{
+			name:   i.Instance.Name,
+			node:   i.NodeName,
+			state:  strings.ToLower(i.Instance.State),
+			image:  i.Instance.Image,
+			ipv4:   i.Instance.Ipv4,
+			labels: labels[i.NodeName],
+		}
+		if info, ok := keys[i.NodeName]; ok {
+			host.user = info.Username
+			host.keyFile = keyPath(keyDir, i.NodeName)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+func keyPath(keyDir string, nodeName string) string {
+	return filepath.Join(keyDir, filepath.Base(nodeName)+".pem")
+}
+
+func writeKey(keyDir string, nodeName string, key string) error {
+	if err := os.MkdirAll(keyDir, 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(keyPath(keyDir, nodeName), []byte(key), 0o600)
+}
+
+func writeSSHConfig(w io.Writer, hosts []*exportHost, proxyCommand string) error {
+	var b strings.Builder
+	b.WriteString("# generated by multiverse export ssh-config\n")
+	for _, h := range hosts {
+		_, _ = fmt.Fprintf(&b, "\nHost %s %s\n", h.name, h.host())
+		_, _ = fmt.Fprintf(&b, "  HostName %s\n", h.host())
+		if h.user != "" {
+			_, _ = fmt.Fprintf(&b, "  User %s\n", h.user)
+			_, _ = fmt.Fprintf(&b, "  IdentityFile %s\n", h.keyFile)
+			b.WriteString("  IdentitiesOnly yes\n")
+		}
+		// instances get new host keys whenever they are launched again
+		b.WriteString("  StrictHostKeyChecking no\n")
+		b.WriteString("  UserKnownHostsFile /dev/null\n")
+		_, _ = fmt.Fprintf(&b, "  ProxyCommand %s\n", proxyCommand)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func groupName(parts ...string) string {
+	return groupNameRegexp.ReplaceAllString(strings.ToLower(strings.Join(parts, "_")), "_")
+}
+
+// groups maps group names to hosts by node, state and node labels.
+func groups(hosts []*exportHost) map[string][]string {
+	groups := make(map[string][]string)
+	for _, h := range hosts {
+		groups[groupName("node", h.node)] = append(groups[groupName("node", h.node)], h.host())
+		groups[groupName("state", h.state)] = append(groups[groupName("state", h.state)], h.host())
+		for key, value := range h.labels {
+			groups[groupName("label", key, value)] = append(groups[groupName("label", key, value)], h.host())
+		}
+	}
+	return groups
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hostVars(h *exportHost) map[string]any {
+	vars := map[string]any{
+		"ansible_host":        h.host(),
+		"multiverse_instance": h.name,
+		"multiverse_node":     h.node,
+		"multiverse_state":    h.state,
+		"multiverse_image":    h.image,
+		"multiverse_ipv4":     h.ipv4,
+	}
+	if h.user != "" {
+		vars["ansible_user"] = h.user
+		vars["ansible_ssh_private_key_file"] = h.keyFile
+	}
+	return vars
+}
+
+func sshArgs(proxyCommand string) string {
+	return fmt.Sprintf("-o ProxyCommand=%q -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null", proxyCommand)
+}
+
+func writeINIInventory(w io.Writer, hosts []*exportHost, proxyCommand string) error {
+	var b strings.Builder
+	b.WriteString("# generated by multiverse export ansible-inventory\n")
+
+	// host vars go on the node groups, every host being in exactly one
+	byHost := make(map[string]*exportHost, len(hosts))
+	for _, h := range hosts {
+		byHost[h.host()] = h
+	}
+
+	groups := groups(hosts)
+	for _, group := range sortedKeys(groups) {
+		_, _ = fmt.Fprintf(&b, "\n[%s]\n", group)
+		for _, host := range groups[group] {
+			b.WriteString(host)
+			if strings.HasPrefix(group, "node_") {
+				vars := hostVars(byHost[host])
+				for _, key := range sortedKeys(vars) {
+					if value, ok := vars[key].(string); ok {
+						_, _ = fmt.Fprintf(&b, " %s=%s", key, shellQuote(value))
+					}
+				}
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, _ = fmt.Fprintf(&b, "\n[all:vars]\nansible_ssh_common_args=%s\n", shellQuote(sshArgs(proxyCommand)))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type inventoryGroup struct {
+	Vars     map[string]any `json:"vars,omitempty"`
+	Hosts    []string       `json:"hosts,omitempty"`
+	Children []string       `json:"children,omitempty"`
+}
+
+// writeJSONInventory writes the --list output of an ansible dynamic
+// inventory, or the vars of host alone when host is set.
+func writeJSONInventory(w io.Writer, hosts []*exportHost, proxyCommand string, host string) error {
+	var inventory any
+	if host != "" {
+		vars := map[string]any{}
+		for _, h := range hosts {
+			if h.host() == host {
+				vars = hostVars(h)
+			}
+		}
+		inventory = vars
+	} else {
+		hostvars := make(map[string]any, len(hosts))
+		for _, h := range hosts {
+			hostvars[h.host()] = hostVars(h)
+		}
+
+		groups := groups(hosts)
+		list := map[string]any{
+			"_meta": map[string]any{"hostvars": hostvars},
+			"all": &inventoryGroup{
+				Children: sortedKeys(groups),
+				Vars:     map[string]any{"ansible_ssh_common_args": sshArgs(proxyCommand)},
+			},
+		}
+		for group, names := range groups {
+			list[group] = &inventoryGroup{Hosts: names}
+		}
+		inventory = list
+	}
+
+	bytes, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(bytes))
+	return err
+}
+
+func exportSSHConfigCommand() *command {
+	var keyDir string
+	fs := flag.NewFlagSet("ssh-config", flag.ContinueOnError)
+	fs.StringVar(&keyDir, "key-dir", defaultKeyDir(), "dir to write the ssh key of each node into")
+
+	return &command{
+		name:  "ssh-config",
+		short: "Print ssh config entries of instances, tunneled through master.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			proxyCommand, err := e.proxyCommand()
+			if err != nil {
+				return err
+			}
+			hosts, err := collectHosts(ctx, e, keyDir)
+			if err != nil {
+				return err
+			}
+			return writeSSHConfig(e.stdout, hosts, proxyCommand)
+		},
+	}
+}
+
+func exportAnsibleInventoryCommand() *command {
+	var keyDir, format, host string
+	var list bool
+	fs := flag.NewFlagSet("ansible-inventory", flag.ContinueOnError)
+	fs.StringVar(&keyDir, "key-dir", defaultKeyDir(), "dir to write the ssh key of each node into")
+	fs.StringVar(&format, "format", exportINI, "inventory format, ini or json")
+	fs.BoolVar(&list, "list", false, "print the whole json inventory, as ansible asks dynamic inventories")
+	fs.StringVar(&host, "host", "", "print the json vars of a host, as ansible asks dynamic inventories")
+
+	return &command{
+		name:  "ansible-inventory",
+		short: "Print ansible inventory of instances grouped by node, state and labels.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if list || host != "" {
+				format = exportJSON
+			}
+			if format != exportINI && format != exportJSON {
+				return usageErrorf("unknown format: %s", format)
+			}
+			proxyCommand, err := e.proxyCommand()
+			if err != nil {
+				return err
+			}
+			hosts, err := collectHosts(ctx, e, keyDir)
+			if err != nil {
+				return err
+			}
+			if format == exportJSON {
+				return writeJSONInventory(e.stdout, hosts, proxyCommand, host)
+			}
+			return writeINIInventory(e.stdout, hosts, proxyCommand)
+		},
+	}
+}
+
+func exportCommand() *command {
+	return &command{
+		name:  "export",
+		short: "Export instances to ssh and ansible.",
+		children: []*command{
+			exportSSHConfigCommand(),
+			exportAnsibleInventoryCommand(),
+		},
+	}
+}
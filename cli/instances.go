@@ -0,0 +1,368 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erayarslan/multiverse/api"
+	"github.com/erayarslan/multiverse/common"
+)
+
+type instanceFilter struct {
+	node  string
+	name  string
+	state string
+}
+
+func (f *instanceFilter) bind(fs *flag.FlagSet, state bool) {
+	fs.StringVar(&f.node, "node", "", "filter instances by node name glob")
+	fs.StringVar(&f.name, "name", "", "filter instances by name glob")
+	if state {
+		fs.StringVar(&f.state, "state", "", "filter instances by state, e.g. running")
+	}
+}
+
+func (f *instanceFilter) match(nodeName string, name string, state string) (bool, error) {
+	if ok, err := match(f.node, nodeName); !ok || err != nil {
+		return false, err
+	}
+	if ok, err := match(f.name, name); !ok || err != nil {
+		return false, err
+	}
+	return f.state == "" || strings.EqualFold(f.state, state), nil
+}
+
+func instanceNames(ctx context.Context, e *env) []string {
+	apiClient, err := e.client()
+	if err != nil {
+		return nil
+	}
+	getInstancesReply, err := apiClient.Instances(ctx)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(getInstancesReply.Instances))
+	for _, i := range getInstancesReply.Instances {
+		names = append(names, i.Instance.Name)
+	}
+	return names
+}
+
+var instanceSorts = map[string]func(a *api.Instance, b *api.Instance) bool{
+	"name":  func(a *api.Instance, b *api.Instance) bool { return a.Instance.Name < b.Instance.Name },
+	"node":  func(a *api.Instance, b *api.Instance) bool { return a.NodeName < b.NodeName },
+	"state": func(a *api.Instance, b *api.Instance) bool { return a.Instance.State < b.Instance.State },
+}
+
+func instancesListCommand() *command {
+	var out output
+	var filter instanceFilter
+	var by string
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	out.bind(fs)
+	filter.bind(fs, true)
+	fs.StringVar(&by, "sort", "name", "sort instances by name, node or state")
+
+	return &command{
+		name:    "list",
+		aliases: []string{"ls"},
+		short:   "List instances.",
+		flags:   fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+			less, ok := instanceSorts[by]
+			if !ok {
+				return usageErrorf("unknown sort: %s", by)
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			getInstancesReply, err := apiClient.Instances(ctx)
+			if err != nil {
+				return err
+			}
+
+			instances := make([]*api.Instance, 0, len(getInstancesReply.Instances))
+			for _, i := range getInstancesReply.Instances {
+				ok, err := filter.match(i.NodeName, i.Instance.Name, i.Instance.State)
+				if err != nil {
+					return err
+				}
+				if ok {
+					instances = append(instances, i)
+				}
+			}
+			sort.SliceStable(instances, func(i, j int) bool { return less(instances[i], instances[j]) })
+
+			t := &table{columns: []column{
+				{name: "Node Name"}, {name: "Instance Name"}, {name: "State"}, {name: "IPv4"}, {name: "Image", wide: true},
+			}}
+			for _, i := range instances {
+				t.append(i.NodeName, i.Instance.Name, i.Instance.State,
+					missingIfEmpty(strings.Join(i.Instance.Ipv4, ",")), i.Instance.Image)
+			}
+
+			return out.print(e.stdout, &api.GetInstancesReply{Instances: instances}, t)
+		},
+	}
+}
+
+func formatOptional[T int32 | int64 | uint64](value *T) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *value)
+}
+
+func formatLoad(load *common.Load) string {
+	if load == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f %.2f %.2f", load.Load1, load.Load5, load.Load15)
+}
+
+func formatUsage(usage *uint64, total *uint64) string {
+	if usage == nil || total == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fGiB out of %.1fGiB", float64(*usage)/gib, float64(*total)/gib)
+}
+
+func formatUptime(seconds *int64) string {
+	if seconds == nil {
+		return "-"
+	}
+	return (time.Duration(*seconds) * time.Second).String()
+}
+
+func instancesInfoCommand() *command {
+	var out output
+	var filter instanceFilter
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	out.bind(fs)
+	filter.bind(fs, false)
+
+	return &command{
+		name:  "info",
+		short: "Show instance usage.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			getInfoReply, err := apiClient.Info(ctx)
+			if err != nil {
+				return err
+			}
+
+			instances := make([]*api.GetInfoInstance, 0, len(getInfoReply.Instances))
+			for _, i := range getInfoReply.Instances {
+				ok, err := filter.match(i.NodeName, i.Instance.Name, "")
+				if err != nil {
+					return err
+				}
+				if ok {
+					instances = append(instances, i)
+				}
+			}
+
+			t := &table{columns: []column{
+				{name: "Node Name"},
+				{name: "Instance Name"},
+				{name: "Cpu"},
+				{name: "Load"},
+				{name: "Disk", wide: true},
+				{name: "Memory"},
+				{name: "Uptime"},
+			}}
+			for _, i := range instances {
+				t.append(i.NodeName, i.Instance.Name,
+					formatOptional(i.Instance.CpuCount),
+					formatLoad(i.Instance.Load),
+					formatUsage(i.Instance.DiskUsage, i.Instance.DiskTotal),
+					formatUsage(i.Instance.MemoryUsage, i.Instance.MemoryTotal),
+					formatUptime(i.Instance.UptimeSeconds),
+				)
+			}
+
+			return out.print(e.stdout, &api.GetInfoReply{Instances: instances}, t)
+		},
+	}
+}
+
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func instancesLaunchCommand() *command {
+	var cpus, memory, disk, nodeSelector, affinity, antiAffinity, tolerations string
+	fs := flag.NewFlagSet("launch", flag.ContinueOnError)
+	fs.StringVar(&cpus, "cpus", "1", "instance num cores")
+	fs.StringVar(&memory, "memory", "1G", "instance mem size")
+	fs.StringVar(&disk, "disk", "4G", "instance disk space")
+	fs.StringVar(&nodeSelector, "node-selector", "", "launch on nodes labeled key=value,key=value")
+	fs.StringVar(&affinity, "affinity", "", "launch next to instances name,name")
+	fs.StringVar(&antiAffinity, "anti-affinity", "", "launch away from instances name,name")
+	fs.StringVar(&tolerations, "tolerations", "", "tolerate taints key=value:Effect,key")
+
+	return &command{
+		name:  "launch",
+		args:  "<name>",
+		short: "Launch instance on a scheduled node.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>"); err != nil {
+				return err
+			}
+			numCores, err := strconv.ParseInt(cpus, 10, 32)
+			if err != nil {
+				return usageErrorf("invalid cpus: %v", err)
+			}
+			selector, err := common.ParseLabels(nodeSelector)
+			if err != nil {
+				return &usageError{err: err}
+			}
+			parsedTolerations, err := common.ParseTolerations(tolerations)
+			if err != nil {
+				return &usageError{err: err}
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			_, err = apiClient.Launch(ctx, &common.LaunchRequest{
+				InstanceName: args[0],
+				NumCores:     int32(numCores),
+				MemSize:      memory,
+				DiskSpace:    disk,
+				NodeSelector: selector,
+				Affinity:     splitList(affinity),
+				AntiAffinity: splitList(antiAffinity),
+				Tolerations:  parsedTolerations,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "instance %s launched\n", args[0])
+			return err
+		},
+	}
+}
+
+func instancesStopCommand() *command {
+	var force bool
+	fs := flag.NewFlagSet("stop", flag.ContinueOnError)
+	fs.BoolVar(&force, "force", false, "force stop instance")
+
+	return &command{
+		name:     "stop",
+		args:     "<name>",
+		short:    "Stop instance.",
+		flags:    fs,
+		complete: instanceNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>"); err != nil {
+				return err
+			}
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			if _, err = apiClient.Stop(ctx, &common.StopRequest{InstanceName: args[0], Force: force}); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "instance %s stopped\n", args[0])
+			return err
+		},
+	}
+}
+
+func instancesShellCommand() *command {
+	return &command{
+		name:     "shell",
+		args:     "<name>",
+		short:    "Open shell in instance.",
+		complete: instanceNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>"); err != nil {
+				return err
+			}
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			return apiClient.Shell(ctx, args[0])
+		},
+	}
+}
+
+func instancesProxyCommand() *command {
+	var port int
+	fs := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	fs.IntVar(&port, "port", 0, "instance tcp port to connect, its ssh port by default")
+
+	return &command{
+		name:     "proxy",
+		args:     "<name>[.<node>]",
+		short:    "Pipe stdin and stdout to an instance port through master, for ssh ProxyCommand.",
+		flags:    fs,
+		complete: instanceNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>[.<node>]"); err != nil {
+				return err
+			}
+			if port < 0 || port > 65535 {
+				return usageErrorf("invalid port: %d", port)
+			}
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			return apiClient.Forward(ctx, args[0], port, os.Stdin, e.stdout)
+		},
+	}
+}
+
+func instancesCommand() *command {
+	return &command{
+		name:    "instances",
+		aliases: []string{"instance"},
+		short:   "Manage instances.",
+		children: []*command{
+			instancesListCommand(),
+			instancesInfoCommand(),
+			instancesLaunchCommand(),
+			instancesStopCommand(),
+			instancesShellCommand(),
+			instancesProxyCommand(),
+		},
+	}
+}
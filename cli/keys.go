@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/erayarslan/multiverse/api"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func keyNames(ctx context.Context, e *env) []string {
+	apiClient, err := e.client()
+	if err != nil {
+		return nil
+	}
+	getKeysReply, err := apiClient.Keys(ctx)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(getKeysReply.Keys))
+	for _, key := range getKeysReply.Keys {
+		names = append(names, key.Name)
+	}
+	return names
+}
+
+// printKeyResults prints what pushing or revoking did on each instance,
+// failing when any instance failed.
+func printKeyResults(e *env, out *output, reply proto.Message, results []*api.KeyResult, done string) error {
+	t := &table{columns: []column{{name: "Instance Name"}, {name: "Node Name"}, {name: "Result"}}}
+	failed := 0
+	for _, r := range results {
+		result := done
+		if r.Error != "" {
+			result = r.Error
+			failed++
+		}
+		t.append(r.InstanceName, r.NodeName, result)
+	}
+
+	if err := out.print(e.stdout, reply, t); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d instance(s) failed to update keys", failed)
+	}
+	return nil
+}
+
+func keysListCommand() *command {
+	var out output
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	out.bind(fs)
+
+	return &command{
+		name:    "list",
+		aliases: []string{"ls"},
+		short:   "List registered ssh keys.",
+		flags:   fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			getKeysReply, err := apiClient.Keys(ctx)
+			if err != nil {
+				return err
+			}
+
+			t := &table{columns: []column{
+				{name: "Name"}, {name: "Fingerprint"}, {name: "Added"}, {name: "Public Key", wide: true},
+			}}
+			for _, key := range getKeysReply.Keys {
+				t.append(key.Name, key.Fingerprint, key.Added.AsTime().Format(timeFormat), key.PublicKey)
+			}
+
+			return out.print(e.stdout, getKeysReply, t)
+		},
+	}
+}
+
+func keysAddCommand() *command {
+	var out output
+	var name string
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	out.bind(fs)
+	fs.StringVar(&name, "name", "", "key name, the key comment when empty")
+
+	return &command{
+		name:  "add",
+		args:  "<public-key-file>",
+		short: "Register ssh key, pushing it to running instances and adding it to new ones.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<public-key-file>"); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+
+			var publicKey []byte
+			var err error
+			if args[0] == "-" {
+				publicKey, err = io.ReadAll(os.Stdin)
+			} else {
+				publicKey, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			addKeyReply, err := apiClient.AddKey(ctx, name, string(publicKey))
+			if err != nil {
+				return err
+			}
+			if out.format == formatTable || out.format == formatWide {
+				_, _ = fmt.Fprintf(e.stdout, "key %s added: %s\n", addKeyReply.Key.Name, addKeyReply.Key.Fingerprint)
+			}
+			return printKeyResults(e, &out, addKeyReply, addKeyReply.Results, "Pushed")
+		},
+	}
+}
+
+func keysRemoveCommand() *command {
+	var out output
+	fs := flag.NewFlagSet("remove", flag.ContinueOnError)
+	out.bind(fs)
+
+	return &command{
+		name:     "remove",
+		aliases:  []string{"rm"},
+		args:     "<name>",
+		short:    "Remove ssh key, revoking it on running instances.",
+		flags:    fs,
+		complete: keyNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<name>"); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			removeKeyReply, err := apiClient.RemoveKey(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return printKeyResults(e, &out, removeKeyReply, removeKeyReply.Results, "Revoked")
+		},
+	}
+}
+
+func keysPushCommand() *command {
+	var out output
+	var instanceName string
+	fs := flag.NewFlagSet("push", flag.ContinueOnError)
+	out.bind(fs)
+	fs.StringVar(&instanceName, "instance", "", "instance to push to, every running instance when empty")
+
+	return &command{
+		name:  "push",
+		short: "Push registered ssh keys to running instances.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			pushKeysReply, err := apiClient.PushKeys(ctx, instanceName)
+			if err != nil {
+				return err
+			}
+			return printKeyResults(e, &out, pushKeysReply, pushKeysReply.Results, "Pushed")
+		},
+	}
+}
+
+func keysCommand() *command {
+	return &command{
+		name:    "keys",
+		aliases: []string{"key"},
+		short:   "Manage ssh keys of users.",
+		children: []*command{
+			keysListCommand(),
+			keysAddCommand(),
+			keysRemoveCommand(),
+			keysPushCommand(),
+		},
+	}
+}
@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/erayarslan/multiverse/api"
+	"github.com/erayarslan/multiverse/common"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const gib = 1024 * 1024 * 1024
+
+// match reports whether name matches the glob pattern, an empty pattern matches everything.
+func match(pattern string, name string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+	ok, err := path.Match(pattern, name)
+	if err != nil {
+		return false, usageErrorf("invalid pattern %q: %v", pattern, err)
+	}
+	return ok, nil
+}
+
+func nodeStatus(n *api.Node) string {
+	status := "Ready"
+	if !n.Ready {
+		status = "NotReady"
+	}
+	if n.Cordoned {
+		status += ",Cordoned"
+	}
+	return status
+}
+
+func nodeNames(ctx context.Context, e *env) []string {
+	apiClient, err := e.client()
+	if err != nil {
+		return nil
+	}
+	getNodesReply, err := apiClient.Nodes(ctx)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(getNodesReply.Nodes))
+	for _, n := range getNodesReply.Nodes {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+var nodeSorts = map[string]func(a *api.Node, b *api.Node) bool{
+	"name": func(a *api.Node, b *api.Node) bool { return a.Name < b.Name },
+	"cpu": func(a *api.Node, b *api.Node) bool {
+		return a.GetResource().GetCpu().GetAvailable() > b.GetResource().GetCpu().GetAvailable()
+	},
+	"memory": func(a *api.Node, b *api.Node) bool {
+		return a.GetResource().GetMemory().GetAvailable() > b.GetResource().GetMemory().GetAvailable()
+	},
+	"disk": func(a *api.Node, b *api.Node) bool {
+		return a.GetResource().GetDisk().GetAvailable() > b.GetResource().GetDisk().GetAvailable()
+	},
+	"last-sync": func(a *api.Node, b *api.Node) bool {
+		return a.GetLastSync().AsTime().After(b.GetLastSync().AsTime())
+	},
+}
+
+func nodesListCommand() *command {
+	var out output
+	var name, status, by string
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	out.bind(fs)
+	fs.StringVar(&name, "name", "", "filter nodes by name glob")
+	fs.StringVar(&status, "status", "", "filter nodes by status, ready, notready or cordoned")
+	fs.StringVar(&by, "sort", "name", "sort nodes by name, cpu, memory, disk or last-sync")
+
+	return &command{
+		name:    "list",
+		aliases: []string{"ls"},
+		short:   "List nodes.",
+		flags:   fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+			less, ok := nodeSorts[by]
+			if !ok {
+				return usageErrorf("unknown sort: %s", by)
+			}
+			if status != "" && status != "ready" && status != "notready" && status != "cordoned" {
+				return usageErrorf("unknown status: %s", status)
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			getNodesReply, err := apiClient.Nodes(ctx)
+			if err != nil {
+				return err
+			}
+
+			nodes := make([]*api.Node, 0, len(getNodesReply.Nodes))
+			for _, n := range getNodesReply.Nodes {
+				ok, err := match(name, n.Name)
+				if err != nil {
+					return err
+				}
+				switch {
+				case !ok,
+					status == "ready" && !n.Ready,
+					status == "notready" && n.Ready,
+					status == "cordoned" && !n.Cordoned:
+					continue
+				}
+				nodes = append(nodes, n)
+			}
+			sort.SliceStable(nodes, func(i, j int) bool { return less(nodes[i], nodes[j]) })
+
+			t := &table{columns: []column{
+				{name: "Node Name"},
+				{name: "Status"},
+				{name: "IPv4", wide: true},
+				{name: "Cpu", wide: true},
+				{name: "Mem", wide: true},
+				{name: "Disk", wide: true},
+				{name: "Cpu Used"},
+				{name: "Mem Used"},
+				{name: "Disk Used"},
+				{name: "Labels", wide: true},
+				{name: "Taints", wide: true},
+				{name: "Version", wide: true},
+				{name: "Last Sync"},
+			}}
+			for _, n := range nodes {
+				taints := make([]string, 0, len(n.Taints))
+				for _, taint := range n.Taints {
+					taints = append(taints, taint.Format())
+				}
+				r := n.GetResource()
+				t.append(
+					n.Name,
+					nodeStatus(n),
+					strings.Join(n.Ipv4, ","),
+					fmt.Sprintf("%d", r.GetCpu().GetAvailable()),
+					fmt.Sprintf("%vGb", r.GetMemory().GetAvailable()/gib),
+					fmt.Sprintf("%vGb", r.GetDisk().GetAvailable()/gib),
+					fmt.Sprintf("%d/%d", r.GetCpu().GetCommitted(), r.GetCpu().GetAllocatable()),
+					fmt.Sprintf("%vGb/%vGb", r.GetMemory().GetCommitted()/gib, r.GetMemory().GetAllocatable()/gib),
+					fmt.Sprintf("%vGb/%vGb", r.GetDisk().GetCommitted()/gib, r.GetDisk().GetAllocatable()/gib),
+					missingIfEmpty(strings.Join(common.FormatLabels(n.Labels), ",")),
+					missingIfEmpty(strings.Join(taints, ",")),
+					fmt.Sprintf("%s (protocol %d)", missingIfEmpty(n.Version), n.Protocol),
+					n.LastSync.AsTime().Format(timeFormat),
+				)
+			}
+
+			return out.print(e.stdout, &api.GetNodesReply{Nodes: nodes}, t)
+		},
+	}
+}
+
+func nodesCordonCommand(cordoned bool) *command {
+	name, short := "cordon", "Exclude node from placement."
+	if !cordoned {
+		name, short = "uncordon", "Include node in placement."
+	}
+
+	return &command{
+		name:     name,
+		args:     "<node>",
+		short:    short,
+		complete: nodeNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<node>"); err != nil {
+				return err
+			}
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			if cordoned {
+				err = apiClient.Cordon(ctx, args[0])
+			} else {
+				err = apiClient.Uncordon(ctx, args[0])
+			}
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "node %s %sed\n", args[0], name)
+			return err
+		},
+	}
+}
+
+func nodesDrainCommand() *command {
+	var out output
+	var action string
+	var timeout time.Duration
+	var force bool
+	fs := flag.NewFlagSet("drain", flag.ContinueOnError)
+	out.bind(fs)
+	fs.StringVar(&action, "action", "stop", "drain action, stop or suspend")
+	fs.DurationVar(&timeout, "timeout", 2*time.Minute, "drain timeout per instance")
+	fs.BoolVar(&force, "force", false, "force stop instances which fail to stop in time")
+
+	return &command{
+		name:     "drain",
+		args:     "<node>",
+		short:    "Cordon node and stop or suspend its instances.",
+		flags:    fs,
+		complete: nodeNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<node>"); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+			drainAction, ok := api.DrainAction_value[strings.ToUpper(action)]
+			if !ok {
+				return usageErrorf("unknown drain action: %s", action)
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			drainReply, err := apiClient.Drain(ctx, &api.DrainRequest{
+				NodeName: args[0],
+				Action:   api.DrainAction(drainAction),
+				Timeout:  durationpb.New(timeout),
+				Force:    force,
+			})
+			if err != nil {
+				return err
+			}
+
+			t := &table{columns: []column{{name: "Instance Name"}, {name: "Result"}}}
+			failed := 0
+			for _, r := range drainReply.Results {
+				result := "Drained"
+				if r.Error != "" {
+					result = r.Error
+					failed++
+				}
+				t.append(r.InstanceName, result)
+			}
+
+			if err = out.print(e.stdout, drainReply, t); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d instance(s) failed to drain", failed)
+			}
+			return nil
+		},
+	}
+}
+
+func nodesTaintCommand() *command {
+	return &command{
+		name:     "taint",
+		args:     "<node> <key=value:Effect>",
+		short:    "Add taint to node.",
+		complete: nodeNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<node>", "<key=value:Effect>"); err != nil {
+				return err
+			}
+			taint, err := common.ParseTaint(args[1])
+			if err != nil {
+				return &usageError{err: err}
+			}
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			if err = apiClient.Taint(ctx, args[0], taint); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "node %s tainted with %s\n", args[0], taint.Format())
+			return err
+		},
+	}
+}
+
+func nodesUntaintCommand() *command {
+	return &command{
+		name:     "untaint",
+		args:     "<node> <key>",
+		short:    "Remove taint from node.",
+		complete: nodeNames,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args, "<node>", "<key>"); err != nil {
+				return err
+			}
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			if err = apiClient.Untaint(ctx, args[0], args[1]); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(e.stdout, "taint %s removed from node %s\n", args[1], args[0])
+			return err
+		},
+	}
+}
+
+func nodesCommand() *command {
+	return &command{
+		name:    "nodes",
+		aliases: []string{"node"},
+		short:   "Manage nodes.",
+		children: []*command{
+			nodesListCommand(),
+			nodesCordonCommand(true),
+			nodesCordonCommand(false),
+			nodesDrainCommand(),
+			nodesTaintCommand(),
+			nodesUntaintCommand(),
+		},
+	}
+}
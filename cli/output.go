@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatTable = "table"
+	formatWide  = "wide"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+const timeFormat = "2006-01-02 15:04:05 MST"
+
+type column struct {
+	name string
+	wide bool
+}
+
+type table struct {
+	columns []column
+	rows    [][]string
+}
+
+func (t *table) append(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+func (t *table) write(w io.Writer, wide bool) error {
+	tw := tabwriter.NewWriter(w, 10, 1, 5, ' ', 0)
+
+	visible := make([]int, 0, len(t.columns))
+	for i, c := range t.columns {
+		if !c.wide || wide {
+			visible = append(visible, i)
+		}
+	}
+
+	line := func(values func(i int) string) error {
+		fields := make([]string, 0, len(visible))
+		for _, i := range visible {
+			fields = append(fields, values(i))
+		}
+		_, err := fmt.Fprintln(tw, strings.Join(fields, "\t"))
+		return err
+	}
+
+	if err := line(func(i int) string { return t.columns[i].name }); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		if err := line(func(i int) string { return row[i] }); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+type output struct {
+	format string
+}
+
+func (o *output) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "o", formatTable, "output format, table, wide, json or yaml")
+}
+
+func (o *output) validate() error {
+	switch o.format {
+	case formatTable, formatWide, formatJSON, formatYAML:
+		return nil
+	default:
+		return usageErrorf("unknown output format: %s", o.format)
+	}
+}
+
+func marshalJSON(message proto.Message) ([]byte, error) {
+	return protojson.MarshalOptions{Multiline: true, Indent: "  ", EmitUnpopulated: true}.Marshal(message)
+}
+
+// marshalYAML goes through protojson so yaml keys and values match the json
+// output, decoding into a yaml.Node keeps the field order of the message and
+// dropping the json styles lets the encoder quote only where needed.
+func marshalYAML(message proto.Message) ([]byte, error) {
+	bytes, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err = yaml.Unmarshal(bytes, &node); err != nil {
+		return nil, err
+	}
+
+	var plain func(n *yaml.Node)
+	plain = func(n *yaml.Node) {
+		n.Style = 0
+		for _, child := range n.Content {
+			plain(child)
+		}
+	}
+	plain(&node)
+
+	return yaml.Marshal(&node)
+}
+
+func (o *output) print(w io.Writer, message proto.Message, t *table) error {
+	switch o.format {
+	case formatJSON:
+		bytes, err := marshalJSON(message)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(bytes))
+		return err
+	case formatYAML:
+		bytes, err := marshalYAML(message)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes)
+		return err
+	default:
+		return t.write(w, o.format == formatWide)
+	}
+}
+
+func missingIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
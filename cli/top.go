@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/erayarslan/multiverse/api"
+	"github.com/erayarslan/multiverse/common"
+
+	"golang.org/x/term"
+)
+
+const (
+	clearScreen  = "\033[H\033[2J"
+	sparkWidth   = 30
+	sortByCPU    = "cpu"
+	sortByMemory = "memory"
+)
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+type topRow struct {
+	name       string
+	node       string
+	cpu        []float64
+	memoryUsed uint64
+	memoryMax  uint64
+	load1      float64
+}
+
+func sparkline(values []float64) string {
+	if len(values) > sparkWidth {
+		values = values[len(values)-sparkWidth:]
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		i := int(v / 100 * float64(len(sparkTicks)))
+		i = max(0, min(i, len(sparkTicks)-1))
+		sb.WriteRune(sparkTicks[i])
+	}
+	return sb.String()
+}
+
+func lastValue(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1]
+}
+
+func topRows(h *common.History) ([]*topRow, []*topRow) {
+	nodes := make(map[string]*topRow)
+	nodeOrder := make([]*topRow, 0)
+	for _, sample := range h.Nodes {
+		row, ok := nodes[sample.NodeName]
+		if !ok {
+			row = &topRow{name: sample.NodeName, node: sample.NodeName}
+			nodes[sample.NodeName] = row
+			nodeOrder = append(nodeOrder, row)
+		}
+		row.cpu = append(row.cpu, sample.CpuPercent)
+		row.memoryUsed = sample.MemoryUsed
+		row.memoryMax = sample.MemoryTotal
+	}
+
+	instances := make(map[string]*topRow)
+	instanceOrder := make([]*topRow, 0)
+	for _, sample := range h.Instances {
+		key := sample.NodeName + "/" + sample.InstanceName
+		row, ok := instances[key]
+		if !ok {
+			row = &topRow{name: sample.InstanceName, node: sample.NodeName}
+			instances[key] = row
+			instanceOrder = append(instanceOrder, row)
+		}
+		row.cpu = append(row.cpu, sample.CpuPercent)
+		row.memoryUsed = sample.MemoryUsage
+		row.memoryMax = sample.MemoryTotal
+		row.load1 = sample.Load1
+	}
+
+	return nodeOrder, instanceOrder
+}
+
+func sortTopRows(rows []*topRow, by string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if by == sortByMemory {
+			return rows[i].memoryUsed > rows[j].memoryUsed
+		}
+		return lastValue(rows[i].cpu) > lastValue(rows[j].cpu)
+	})
+}
+
+func formatMemory(used uint64, total uint64) string {
+	return fmt.Sprintf("%.1fGiB/%.1fGiB", float64(used)/1024/1024/1024, float64(total)/1024/1024/1024)
+}
+
+func renderTop(h *common.History, by string) string {
+	nodes, instances := topRows(h)
+	sortTopRows(nodes, by)
+	sortTopRows(instances, by)
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 10, 1, 3, ' ', 0)
+
+	_, _ = fmt.Fprintf(w, "multiverse top - %s - sorted by %s (c: cpu, m: memory, q: quit)\n\n",
+		time.Now().Format("15:04:05"), by)
+
+	fs := "%s\t%s\t%s\t%s\n"
+	_, _ = fmt.Fprintf(w, fs, "Node Name", "Cpu%", "Mem", "Cpu History")
+	for _, r := range nodes {
+		_, _ = fmt.Fprintf(w, fs, r.name, fmt.Sprintf("%.1f", lastValue(r.cpu)),
+			formatMemory(r.memoryUsed, r.memoryMax), sparkline(r.cpu))
+	}
+	_, _ = fmt.Fprintln(w)
+
+	fs = "%s\t%s\t%s\t%s\t%s\t%s\n"
+	_, _ = fmt.Fprintf(w, fs, "Instance Name", "Node Name", "Cpu%", "Mem", "Load", "Cpu History")
+	for _, r := range instances {
+		_, _ = fmt.Fprintf(w, fs, r.name, r.node, fmt.Sprintf("%.1f", lastValue(r.cpu)),
+			formatMemory(r.memoryUsed, r.memoryMax), fmt.Sprintf("%.2f", r.load1), sparkline(r.cpu))
+	}
+	_ = w.Flush()
+
+	// the terminal is in raw mode, so line feeds do not return the carriage
+	return strings.ReplaceAll(buf.String(), "\n", "\r\n")
+}
+
+func topCommand() *command {
+	var by string
+	var interval time.Duration
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+	fs.StringVar(&by, "sort", sortByCPU, "sort order, cpu or memory")
+	fs.DurationVar(&interval, "interval", 2*time.Second, "refresh interval")
+
+	return &command{
+		name:  "top",
+		short: "Show live node and instance usage.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if by != sortByCPU && by != sortByMemory {
+				return usageErrorf("unknown sort: %s", by)
+			}
+			if interval <= 0 {
+				return usageErrorf("interval must be positive")
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			return top(ctx, e, apiClient, by, interval)
+		},
+	}
+}
+
+func top(ctx context.Context, e *env, apiClient api.Client, by string, interval time.Duration) error {
+	stdInFd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(stdInFd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := term.Restore(stdInFd, state); err != nil {
+			log.Printf("failed to restore terminal: %v", err)
+		}
+	}()
+
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h, err := apiClient.History(ctx, &api.GetHistoryRequest{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			_, _ = fmt.Fprintf(e.stdout, "%serror while history: %v\r\n", clearScreen, err)
+		} else {
+			_, _ = fmt.Fprint(e.stdout, clearScreen+renderTop(h, by))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key := <-keys:
+			switch key {
+			case 'c':
+				by = sortByCPU
+			case 'm':
+				by = sortByMemory
+			case 'q', 3:
+				return nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
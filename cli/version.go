@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/erayarslan/multiverse/api"
+	"github.com/erayarslan/multiverse/common"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func versionCommand() *command {
+	var out output
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	out.bind(fs)
+
+	return &command{
+		name:  "version",
+		short: "Show client and master versions.",
+		flags: fs,
+		run: func(ctx context.Context, e *env, args []string) error {
+			if err := exactArgs(args); err != nil {
+				return err
+			}
+			if err := out.validate(); err != nil {
+				return err
+			}
+
+			apiClient, err := e.client()
+			if err != nil {
+				return err
+			}
+			getVersionReply, err := apiClient.Version(ctx)
+			// masters older than versioning still answer everything else
+			if status.Code(err) == codes.Unimplemented {
+				getVersionReply, err = &api.GetVersionReply{}, nil
+			}
+			if err != nil {
+				return err
+			}
+
+			t := &table{columns: []column{
+				{name: "Component"}, {name: "Version"}, {name: "Protocol"}, {name: "Capabilities", wide: true},
+			}}
+			t.append(
+				"client",
+				common.Version,
+				fmt.Sprintf("%d (min %d)", common.ProtocolVersion, common.MinProtocolVersion),
+				strings.Join(common.Capabilities, ","),
+			)
+			master := "master"
+			if getVersionReply.Version != "" && !getVersionReply.Leader {
+				master = "master (follower)"
+			}
+			t.append(
+				master,
+				missingIfEmpty(getVersionReply.Version),
+				fmt.Sprintf("%d (min %d)", getVersionReply.Protocol, getVersionReply.MinProtocol),
+				missingIfEmpty(strings.Join(getVersionReply.Capabilities, ",")),
+			)
+
+			return out.print(e.stdout, getVersionReply, t)
+		},
+	}
+}
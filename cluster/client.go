@@ -2,134 +2,330 @@ package cluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/erayarslan/multiverse/agent"
 	"github.com/erayarslan/multiverse/common"
+	"github.com/erayarslan/multiverse/metrics"
 	"github.com/erayarslan/multiverse/multipass"
 
 	"google.golang.org/grpc/metadata"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	leaveTimeout     = 5 * time.Second
+	initialBackoff   = time.Second
+	fullSyncInterval = 5 * time.Minute
 )
 
 type client struct {
-	stream          grpc.BidiStreamingClient[SyncRequest, SyncReply]
-	client          RpcClient
+	lastFullSync    time.Time
 	agentServer     agent.Server
 	multipassClient multipass.Client
 	state           agent.State
+	stream          grpc.BidiStreamingClient[SyncRequest, SyncReply]
+	synced          *State
 	conn            *grpc.ClientConn
-	uuid            string
+	conns           []*grpc.ClientConn
+	labels          map[string]string
 	nodeName        string
-	closed          bool
+	uuid            string
+	master          common.PeerVersion
+	backoff         Backoff
+	labelsMu        sync.RWMutex
+	syncedMu        sync.Mutex
+	sendMu          sync.Mutex
+	closed          atomic.Bool
+}
+
+// Backoff bounds reconnects to master, zero MaxAttempts never gives up.
+type Backoff struct {
+	Max         time.Duration
+	MaxAttempts int
 }
 
 type Client interface {
 	Sync() error
+	SetLabels(labels map[string]string) error
 	Close() error
 }
 
+func (c *client) send(req *SyncRequest) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.stream == nil {
+		return nil
+	}
+	return c.stream.Send(req)
+}
+
+// SetLabels replaces the advertised labels, pushing them to master on the
+// current stream and on join after reconnecting.
+func (c *client) SetLabels(labels map[string]string) error {
+	c.labelsMu.Lock()
+	c.labels = labels
+	c.labelsMu.Unlock()
+
+	return c.send(&SyncRequest{Labels: &Labels{Items: labels}})
+}
+
+// Close tells master the worker is leaving before closing the stream.
 func (c *client) Close() error {
-	c.closed = true
+	c.closed.Store(true)
+	master := c.masterVersion()
+
+	c.sendMu.Lock()
 	if c.stream != nil {
+		if !master.Has(common.CapabilityLeave) {
+			log.Printf("master %s does not support leaving, it will see the node as not ready", master)
+		} else if err := c.stream.Send(&SyncRequest{Leaving: true}); err != nil {
+			log.Printf("error while sending leaving: %v", err)
+		}
 		if err := c.stream.CloseSend(); err != nil {
 			log.Printf("error while closing stream: %v", err)
 		}
+		// wait for master to end the stream so leaving is not lost
+		select {
+		case <-c.stream.Context().Done():
+		case <-time.After(leaveTimeout):
+		}
 	}
-	return c.conn.Close()
+	c.sendMu.Unlock()
+
+	errs := make([]error, 0, len(c.conns))
+	for _, conn := range c.conns {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
 }
 
+// Sync keeps the worker joined to master, reconnecting with capped
+// exponential backoff and jitter until closed or out of attempts. With
+// several masters every one is tried before backing off, the leader taking
+// the worker and the others refusing it.
 func (c *client) Sync() error {
-	if !c.isReady() {
-		return fmt.Errorf("could not connect")
-	}
-	if err := c.sync(); err != nil && !c.closed {
-		log.Printf("error while sync: %v", err)
-		log.Printf("reconnecting...")
-		return c.Sync()
+	delay := initialBackoff
+	tried := 0
+	for attempt := 1; !c.closed.Load(); attempt++ {
+		// the channel keeps its own backoff, reset it so this attempt dials now
+		c.conn.ResetConnectBackoff()
+		joined, err := c.sync()
+		if c.closed.Load() {
+			return nil
+		}
+		// master refusing an old worker heals once either side is upgraded
+		if status.Code(err) == codes.FailedPrecondition {
+			log.Printf("master refused this worker, upgrade it: %v", err)
+		}
+		if err == nil {
+			err = fmt.Errorf("stream closed by master")
+		}
+		if joined {
+			attempt, delay, tried = 1, initialBackoff, 0
+		}
+		log.Printf("error while sync with %s: %v", c.conn.Target(), err)
+		c.next()
+		if tried++; tried < len(c.conns) {
+			attempt--
+			continue
+		}
+		tried = 0
+		if c.backoff.MaxAttempts > 0 && attempt >= c.backoff.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		wait := jitter(delay)
+		log.Printf("reconnecting in %v...", wait)
+		metrics.SyncReconnects.Inc()
+		time.Sleep(wait)
+		delay = min(delay*2, c.backoff.Max)
 	}
 	return nil
 }
 
-func (c *client) isReady() bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	ticker := time.NewTicker(time.Second)
-	for {
-		select {
-		case <-ticker.C:
-			c.conn.Connect()
-			if c.conn.GetState() == connectivity.Ready {
-				return true
-			}
-		case <-ctx.Done():
-			return false
+// next moves on to the next master.
+func (c *client) next() {
+	for i, conn := range c.conns {
+		if conn == c.conn {
+			c.conn = c.conns[(i+1)%len(c.conns)]
+			break
 		}
 	}
 }
 
-func (c *client) sync() error {
-	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(
+// jitter spreads reconnects of many workers over [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	return d/2 + rand.N(d/2+1)
+}
+
+// sync joins master and listens until the stream ends, reporting whether
+// the join succeeded.
+func (c *client) sync() (bool, error) {
+	md := metadata.Join(common.VersionMetadata(), metadata.Pairs(
 		"nodeName", c.nodeName,
 		"agentPort", strconv.Itoa(c.agentServer.Port()),
 	))
+	c.labelsMu.RLock()
+	md.Append("labels", common.FormatLabels(c.labels)...)
+	c.labelsMu.RUnlock()
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), md))
+	defer cancel()
 
-	var err error
-	c.stream, err = c.client.Sync(ctx)
+	rpcClient := NewRpcClient(c.conn)
+	stream, err := rpcClient.Sync(ctx)
+	if err != nil {
+		return false, err
+	}
+	header, err := stream.Header()
 	if err != nil {
-		return err
+		return false, err
 	}
+	// older masters are served by leaving out what they do not advertise
+	master := common.PeerVersionFromMetadata(header)
+	if master.Protocol < common.ProtocolVersion {
+		log.Printf("master %s is older than this worker, falling back for missing capabilities", master)
+	}
+	c.syncedMu.Lock()
+	c.master = master
+	c.syncedMu.Unlock()
+	c.sendMu.Lock()
+	c.stream = stream
+	c.sendMu.Unlock()
+	defer func() {
+		c.sendMu.Lock()
+		c.stream = nil
+		c.sendMu.Unlock()
+	}()
 
-	return common.ListenBidiClient(c.stream, func(res *SyncReply) error {
+	joined := false
+	err = common.ListenBidiClient(stream, func(res *SyncReply) error {
+		if res.Resync {
+			log.Printf("master asked for full state")
+			c.pushState(c.state.Snapshot(), true)
+			return nil
+		}
 		c.uuid = res.Uuid
-		log.Printf("joined with uuid: %s", c.uuid)
+		joined = true
+		log.Printf("joined with uuid: %s, master: %s", c.uuid, master)
+		c.pushState(c.state.Snapshot(), true)
+		if master.Has(common.CapabilityTunnel) {
+			go c.tunnel(ctx, rpcClient, res.Uuid)
+		} else {
+			log.Printf("master %s does not support tunnels, it dials the agent instead", master)
+		}
 		return nil
 	})
+	return joined, err
 }
 
-func (c *client) stateSync() {
-	for state := range c.state.Listen() { //nolint:govet
-		if c.closed {
-			continue
-		}
+// tunnel carries the agent rpc over the worker's connection until the sync
+// stream of ctx ends.
+func (c *client) tunnel(ctx context.Context, rpcClient RpcClient, uid string) {
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(ctx, metadata.Pairs("uuid", uid)))
+	stream, err := rpcClient.Tunnel(ctx)
+	if err != nil {
+		cancel()
+		log.Printf("error while opening agent tunnel: %v", err)
+		return
+	}
 
-		if c.stream == nil {
-			continue
+	conn := newTunnelConn(stream, c.nodeName, cancel)
+	if err = c.agentServer.ServeTunnel(conn); err != nil {
+		_ = conn.Close()
+		log.Printf("error while serving agent tunnel: %v", err)
+	}
+}
+
+// pushState sends snapshot as a delta on the state master holds, or in full
+// when asked to, on first push and once fullSyncInterval passes.
+func (c *client) pushState(snapshot agent.Snapshot, full bool) {
+	if snapshot.Resource == nil {
+		return
+	}
+
+	c.syncedMu.Lock()
+	defer c.syncedMu.Unlock()
+
+	next := &State{
+		Instances: snapshot.Instances,
+		Resource:  snapshot.Resource,
+		Version:   c.synced.GetVersion() + 1,
+	}
+
+	req := &SyncRequest{State: next}
+	if !full && c.master.Has(common.CapabilityDelta) && c.synced != nil && time.Since(c.lastFullSync) < fullSyncInterval {
+		delta := diffState(c.synced, next)
+		if isEmptyDelta(delta) {
+			// an empty delta still tells master the worker is alive
+			delta.Version = delta.BaseVersion
 		}
+		next = applyDelta(c.synced, delta)
+		req = &SyncRequest{Delta: delta}
+	}
+
+	if err := c.send(req); err != nil {
+		log.Printf("error while sending state: %v", err)
+		return
+	}
+
+	c.synced = next
+	if req.State != nil {
+		c.lastFullSync = time.Now()
+	}
+}
 
-		if err := c.stream.Send(&SyncRequest{
-			State: &State{
-				Instances: state.Instances,
-				Resource:  state.Resource,
-			},
-		}); err != nil {
-			log.Printf("error while sending state: %v", err)
+func (c *client) masterVersion() common.PeerVersion {
+	c.syncedMu.Lock()
+	defer c.syncedMu.Unlock()
+	return c.master
+}
+
+func (c *client) stateSync() {
+	for snapshot := range c.state.Listen() {
+		if c.closed.Load() {
+			continue
 		}
+		c.pushState(snapshot, false)
 	}
 }
 
-func NewClient(addr string, nodeName string,
-	agentServer agent.Server, multipassClient multipass.Client, state agent.State,
+func NewClient(addr string, nodeName string, labels map[string]string,
+	agentServer agent.Server, multipassClient multipass.Client, state agent.State, backoff Backoff,
 ) (Client, error) {
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-	conn, err := grpc.NewClient(addr, opts...)
-	if err != nil {
-		return nil, err
+	conns := make([]*grpc.ClientConn, 0)
+	for _, addr := range common.ParseAddrs(addr) {
+		conn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
 	}
-	rpcClient := NewRpcClient(conn)
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("no master addr to connect")
+	}
+
 	c := &client{
-		conn:            conn,
-		client:          rpcClient,
+		conn:            conns[0],
+		conns:           conns,
 		agentServer:     agentServer,
 		nodeName:        nodeName,
+		labels:          labels,
 		multipassClient: multipassClient,
 		state:           state,
+		backoff:         backoff,
 	}
 	go c.stateSync()
 	return c, nil
@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Rpc_Sync_FullMethodName = "/cluster.Rpc/sync"
+	Rpc_Sync_FullMethodName   = "/cluster.Rpc/sync"
+	Rpc_Tunnel_FullMethodName = "/cluster.Rpc/tunnel"
 )
 
 // RpcClient is the client API for Rpc service.
@@ -27,6 +28,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RpcClient interface {
 	Sync(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SyncRequest, SyncReply], error)
+	// tunnel carries the agent rpc of a joined worker over its own connection
+	Tunnel(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TunnelFrame, TunnelFrame], error)
 }
 
 type rpcClient struct {
@@ -50,11 +53,26 @@ func (c *rpcClient) Sync(ctx context.Context, opts ...grpc.CallOption) (grpc.Bid
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Rpc_SyncClient = grpc.BidiStreamingClient[SyncRequest, SyncReply]
 
+func (c *rpcClient) Tunnel(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TunnelFrame, TunnelFrame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Rpc_ServiceDesc.Streams[1], Rpc_Tunnel_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TunnelFrame, TunnelFrame]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_TunnelClient = grpc.BidiStreamingClient[TunnelFrame, TunnelFrame]
+
 // RpcServer is the server API for Rpc service.
 // All implementations must embed UnimplementedRpcServer
 // for forward compatibility.
 type RpcServer interface {
 	Sync(grpc.BidiStreamingServer[SyncRequest, SyncReply]) error
+	// tunnel carries the agent rpc of a joined worker over its own connection
+	Tunnel(grpc.BidiStreamingServer[TunnelFrame, TunnelFrame]) error
 	mustEmbedUnimplementedRpcServer()
 }
 
@@ -68,6 +86,9 @@ type UnimplementedRpcServer struct{}
 func (UnimplementedRpcServer) Sync(grpc.BidiStreamingServer[SyncRequest, SyncReply]) error {
 	return status.Errorf(codes.Unimplemented, "method Sync not implemented")
 }
+func (UnimplementedRpcServer) Tunnel(grpc.BidiStreamingServer[TunnelFrame, TunnelFrame]) error {
+	return status.Errorf(codes.Unimplemented, "method Tunnel not implemented")
+}
 func (UnimplementedRpcServer) mustEmbedUnimplementedRpcServer() {}
 func (UnimplementedRpcServer) testEmbeddedByValue()             {}
 
@@ -96,6 +117,13 @@ func _Rpc_Sync_Handler(srv interface{}, stream grpc.ServerStream) error {
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Rpc_SyncServer = grpc.BidiStreamingServer[SyncRequest, SyncReply]
 
+func _Rpc_Tunnel_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RpcServer).Tunnel(&grpc.GenericServerStream[TunnelFrame, TunnelFrame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Rpc_TunnelServer = grpc.BidiStreamingServer[TunnelFrame, TunnelFrame]
+
 // Rpc_ServiceDesc is the grpc.ServiceDesc for Rpc service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -110,6 +138,12 @@ var Rpc_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "tunnel",
+			Handler:       _Rpc_Tunnel_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "cluster/cluster.proto",
 }
@@ -0,0 +1,182 @@
+package cluster
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/erayarslan/multiverse/common"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const collectInfoTimeout = 5 * time.Second
+
+var (
+	nodeLabels     = []string{"node"}
+	resourceLabels = []string{"node", "type"}
+	instanceLabels = []string{"node", "instance"}
+
+	nodeReadyDesc = prometheus.NewDesc("multiverse_node_ready",
+		"Whether the node is syncing with master.", nodeLabels, nil)
+	nodeCordonedDesc = prometheus.NewDesc("multiverse_node_cordoned",
+		"Whether the node is excluded from placement.", nodeLabels, nil)
+	nodeLastSyncDesc = prometheus.NewDesc("multiverse_node_last_sync_timestamp_seconds",
+		"Last time the node synced its state.", nodeLabels, nil)
+	nodeCPUDesc = prometheus.NewDesc("multiverse_node_cpu_cores",
+		"Node cpu cores by type.", resourceLabels, nil)
+	nodeMemoryDesc = prometheus.NewDesc("multiverse_node_memory_bytes",
+		"Node memory by type.", resourceLabels, nil)
+	nodeDiskDesc = prometheus.NewDesc("multiverse_node_disk_bytes",
+		"Node disk by type.", resourceLabels, nil)
+	instancesDesc = prometheus.NewDesc("multiverse_instances",
+		"Number of instances by state.", []string{"node", "state"}, nil)
+	instanceMemoryUsageDesc = prometheus.NewDesc("multiverse_instance_memory_usage_bytes",
+		"Instance memory usage.", instanceLabels, nil)
+	instanceMemoryTotalDesc = prometheus.NewDesc("multiverse_instance_memory_total_bytes",
+		"Instance memory total.", instanceLabels, nil)
+	instanceDiskUsageDesc = prometheus.NewDesc("multiverse_instance_disk_usage_bytes",
+		"Instance disk usage.", instanceLabels, nil)
+	instanceDiskTotalDesc = prometheus.NewDesc("multiverse_instance_disk_total_bytes",
+		"Instance disk total.", instanceLabels, nil)
+	instanceLoadDesc = prometheus.NewDesc("multiverse_instance_load",
+		"Instance load average.", []string{"node", "instance", "period"}, nil)
+	instanceCPUCountDesc = prometheus.NewDesc("multiverse_instance_cpu_count",
+		"Instance cpu count.", instanceLabels, nil)
+	instanceCPUSecondsDesc = prometheus.NewDesc("multiverse_instance_cpu_seconds_total",
+		"Instance cpu time by mode.", []string{"node", "instance", "mode"}, nil)
+	instanceUptimeDesc = prometheus.NewDesc("multiverse_instance_uptime_seconds",
+		"Instance uptime.", instanceLabels, nil)
+)
+
+type collector struct {
+	server Server
+}
+
+type collectedWorker struct {
+	workerInfo *WorkerInfo
+	instances  map[string]int
+	cpu        []int32
+	memory     []uint64
+	disk       []uint64
+	lastSync   float64
+	ready      bool
+	cordoned   bool
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func gauge(ch chan<- prometheus.Metric, desc *prometheus.Desc, value float64, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var resourceTypes = []string{"total", "available", "allocatable", "committed"}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	workers := make([]*collectedWorker, 0)
+	c.server.IterateWorkers(func(workerInfo *WorkerInfo) bool {
+		resource := workerInfo.State.GetResource()
+		w := &collectedWorker{
+			workerInfo: workerInfo,
+			lastSync:   float64(workerInfo.LastSync.AsTime().Unix()),
+			ready:      workerInfo.Ready,
+			cordoned:   workerInfo.Cordoned,
+			instances:  make(map[string]int),
+			cpu: []int32{
+				resource.GetCpu().GetTotal(), resource.GetCpu().GetAvailable(),
+				resource.GetCpu().GetAllocatable(), resource.GetCpu().GetCommitted(),
+			},
+			memory: []uint64{
+				resource.GetMemory().GetTotal(), resource.GetMemory().GetAvailable(),
+				resource.GetMemory().GetAllocatable(), resource.GetMemory().GetCommitted(),
+			},
+			disk: []uint64{
+				resource.GetDisk().GetTotal(), resource.GetDisk().GetAvailable(),
+				resource.GetDisk().GetAllocatable(), resource.GetDisk().GetCommitted(),
+			},
+		}
+		for _, instance := range workerInfo.State.Instances {
+			w.instances[instance.State]++
+		}
+		workers = append(workers, w)
+		return true
+	})
+
+	for _, w := range workers {
+		nodeName := w.workerInfo.NodeName
+		gauge(ch, nodeReadyDesc, boolToFloat(w.ready), nodeName)
+		gauge(ch, nodeCordonedDesc, boolToFloat(w.cordoned), nodeName)
+		gauge(ch, nodeLastSyncDesc, w.lastSync, nodeName)
+		for i, t := range resourceTypes {
+			gauge(ch, nodeCPUDesc, float64(w.cpu[i]), nodeName, t)
+			gauge(ch, nodeMemoryDesc, float64(w.memory[i]), nodeName, t)
+			gauge(ch, nodeDiskDesc, float64(w.disk[i]), nodeName, t)
+		}
+		for state, count := range w.instances {
+			gauge(ch, instancesDesc, float64(count), nodeName, state)
+		}
+		c.collectInfo(ch, w.workerInfo)
+	}
+}
+
+func (c *collector) collectInfo(ch chan<- prometheus.Metric, workerInfo *WorkerInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectInfoTimeout)
+	defer cancel()
+
+	info, err := workerInfo.AgentClient.Info(ctx, &common.GetInfoRequest{})
+	if err != nil {
+		log.Printf("failed to collect info of node %s: %v", workerInfo.NodeName, err)
+		return
+	}
+
+	for _, instance := range info.Instances {
+		nodeName := workerInfo.NodeName
+		if instance.MemoryUsage != nil {
+			gauge(ch, instanceMemoryUsageDesc, float64(*instance.MemoryUsage), nodeName, instance.Name)
+		}
+		if instance.MemoryTotal != nil {
+			gauge(ch, instanceMemoryTotalDesc, float64(*instance.MemoryTotal), nodeName, instance.Name)
+		}
+		if instance.DiskUsage != nil {
+			gauge(ch, instanceDiskUsageDesc, float64(*instance.DiskUsage), nodeName, instance.Name)
+		}
+		if instance.DiskTotal != nil {
+			gauge(ch, instanceDiskTotalDesc, float64(*instance.DiskTotal), nodeName, instance.Name)
+		}
+		if instance.CpuCount != nil {
+			gauge(ch, instanceCPUCountDesc, float64(*instance.CpuCount), nodeName, instance.Name)
+		}
+		if instance.UptimeSeconds != nil {
+			gauge(ch, instanceUptimeDesc, float64(*instance.UptimeSeconds), nodeName, instance.Name)
+		}
+		if load := instance.Load; load != nil {
+			gauge(ch, instanceLoadDesc, load.Load1, nodeName, instance.Name, "1m")
+			gauge(ch, instanceLoadDesc, load.Load5, nodeName, instance.Name, "5m")
+			gauge(ch, instanceLoadDesc, load.Load15, nodeName, instance.Name, "15m")
+		}
+		if t := instance.CpuTimes; t != nil {
+			for mode, seconds := range map[string]float64{
+				"user": t.User, "nice": t.Nice, "system": t.System, "idle": t.Idle,
+				"iowait": t.Iowait, "irq": t.Irq, "softirq": t.Softirq, "steal": t.Steal,
+			} {
+				ch <- prometheus.MustNewConstMetric(instanceCPUSecondsDesc, prometheus.CounterValue, seconds,
+					nodeName, instance.Name, mode)
+			}
+		}
+	}
+}
+
+func NewCollector(server Server) prometheus.Collector {
+	return &collector{
+		server: server,
+	}
+}
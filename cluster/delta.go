@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"github.com/erayarslan/multiverse/agent"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// resourceThreshold is the share of the total a memory or disk availability
+// has to move by before it is sent in a delta.
+const resourceThreshold = 0.05
+
+func movedBeyondThreshold(prev uint64, next uint64, total uint64) bool {
+	diff := float64(next) - float64(prev)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff > float64(total)*resourceThreshold
+}
+
+func resourceChanged(prev *agent.Resource, next *agent.Resource) bool {
+	if prev == nil || next == nil {
+		return prev != next
+	}
+
+	pc, nc := prev.GetCpu(), next.GetCpu()
+	pm, nm := prev.GetMemory(), next.GetMemory()
+	pd, nd := prev.GetDisk(), next.GetDisk()
+
+	// capacity and commitments drive scheduling, so any change counts
+	if !proto.Equal(pc, nc) ||
+		pm.GetTotal() != nm.GetTotal() || pm.GetAllocatable() != nm.GetAllocatable() || pm.GetCommitted() != nm.GetCommitted() ||
+		pd.GetTotal() != nd.GetTotal() || pd.GetAllocatable() != nd.GetAllocatable() || pd.GetCommitted() != nd.GetCommitted() {
+		return true
+	}
+
+	return movedBeyondThreshold(pm.GetAvailable(), nm.GetAvailable(), nm.GetTotal()) ||
+		movedBeyondThreshold(pd.GetAvailable(), nd.GetAvailable(), nd.GetTotal())
+}
+
+// diffState returns the delta from prev to next, next.Version being the
+// version the delta moves to.
+func diffState(prev *State, next *State) *StateDelta {
+	delta := &StateDelta{
+		BaseVersion: prev.GetVersion(),
+		Version:     next.GetVersion(),
+	}
+
+	old := make(map[string]*agent.Instance, len(prev.GetInstances()))
+	for _, instance := range prev.GetInstances() {
+		old[instance.Name] = instance
+	}
+
+	for _, instance := range next.GetInstances() {
+		if prevInstance, ok := old[instance.Name]; !ok || !proto.Equal(prevInstance, instance) {
+			delta.Upserted = append(delta.Upserted, instance)
+		}
+		delete(old, instance.Name)
+	}
+
+	for _, instance := range prev.GetInstances() {
+		if _, ok := old[instance.Name]; ok {
+			delta.Removed = append(delta.Removed, instance.Name)
+		}
+	}
+
+	if resourceChanged(prev.GetResource(), next.GetResource()) {
+		delta.Resource = next.GetResource()
+	}
+
+	return delta
+}
+
+func isEmptyDelta(delta *StateDelta) bool {
+	return len(delta.Upserted) == 0 && len(delta.Removed) == 0 && delta.Resource == nil
+}
+
+// applyDelta returns a new state with delta applied on state, keeping the
+// instance order of state and appending new instances.
+func applyDelta(state *State, delta *StateDelta) *State {
+	removed := make(map[string]bool, len(delta.Removed))
+	for _, name := range delta.Removed {
+		removed[name] = true
+	}
+
+	upserted := make(map[string]*agent.Instance, len(delta.Upserted))
+	for _, instance := range delta.Upserted {
+		upserted[instance.Name] = instance
+	}
+
+	instances := make([]*agent.Instance, 0, len(state.Instances)+len(delta.Upserted))
+	for _, instance := range state.Instances {
+		if removed[instance.Name] {
+			continue
+		}
+		if next, ok := upserted[instance.Name]; ok {
+			instance = next
+			delete(upserted, instance.Name)
+		}
+		instances = append(instances, instance)
+	}
+	for _, instance := range delta.Upserted {
+		if _, ok := upserted[instance.Name]; ok {
+			instances = append(instances, instance)
+		}
+	}
+
+	resource := state.Resource
+	if delta.Resource != nil {
+		resource = delta.Resource
+	}
+
+	return &State{
+		Resource:  resource,
+		Instances: instances,
+		Version:   delta.Version,
+	}
+}
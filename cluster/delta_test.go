@@ -0,0 +1,195 @@
+package cluster
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/erayarslan/multiverse/agent"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const gib = 1 << 30
+
+func instance(name string, state string) *agent.Instance {
+	return &agent.Instance{Name: name, State: state}
+}
+
+func resource(memoryAvailable uint64, cpuCommitted int32) *agent.Resource {
+	return &agent.Resource{
+		Cpu:    &agent.CPU{Total: 8, Allocatable: 8, Committed: cpuCommitted},
+		Memory: &agent.Memory{Total: 100 * gib, Available: memoryAvailable, Allocatable: 100 * gib},
+		Disk:   &agent.Disk{Total: 100 * gib, Available: 50 * gib, Allocatable: 100 * gib},
+	}
+}
+
+func names(instances []*agent.Instance) []string {
+	n := make([]string, 0, len(instances))
+	for _, i := range instances {
+		n = append(n, i.Name+"="+i.State)
+	}
+	return n
+}
+
+func TestDiffState(t *testing.T) {
+	base := &State{
+		Version:   3,
+		Resource:  resource(50*gib, 2),
+		Instances: []*agent.Instance{instance("a", "Running"), instance("b", "Stopped")},
+	}
+
+	tests := []struct {
+		next         *State
+		name         string
+		wantUpserted []string
+		wantRemoved  []string
+		wantResource bool
+	}{
+		{
+			name:         "unchanged",
+			next:         &State{Version: 4, Resource: resource(50*gib, 2), Instances: base.Instances},
+			wantUpserted: []string{},
+			wantRemoved:  []string{},
+		},
+		{
+			name: "state changed",
+			next: &State{Version: 4, Resource: resource(50*gib, 2), Instances: []*agent.Instance{
+				instance("a", "Stopped"), instance("b", "Stopped"),
+			}},
+			wantUpserted: []string{"a=Stopped"},
+			wantRemoved:  []string{},
+		},
+		{
+			name: "added and removed",
+			next: &State{Version: 4, Resource: resource(50*gib, 2), Instances: []*agent.Instance{
+				instance("a", "Running"), instance("c", "Starting"),
+			}},
+			wantUpserted: []string{"c=Starting"},
+			wantRemoved:  []string{"b"},
+		},
+		{
+			name:         "memory moved within threshold",
+			next:         &State{Version: 4, Resource: resource(54*gib, 2), Instances: base.Instances},
+			wantUpserted: []string{},
+			wantRemoved:  []string{},
+		},
+		{
+			name:         "memory moved beyond threshold",
+			next:         &State{Version: 4, Resource: resource(56*gib, 2), Instances: base.Instances},
+			wantUpserted: []string{},
+			wantRemoved:  []string{},
+			wantResource: true,
+		},
+		{
+			name:         "commitment changed",
+			next:         &State{Version: 4, Resource: resource(50*gib, 3), Instances: base.Instances},
+			wantUpserted: []string{},
+			wantRemoved:  []string{},
+			wantResource: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := diffState(base, tt.next)
+
+			if delta.BaseVersion != 3 || delta.Version != 4 {
+				t.Errorf("versions = %d -> %d, want 3 -> 4", delta.BaseVersion, delta.Version)
+			}
+			if got := names(delta.Upserted); !slices.Equal(got, tt.wantUpserted) {
+				t.Errorf("upserted = %v, want %v", got, tt.wantUpserted)
+			}
+			if !slices.Equal(delta.Removed, tt.wantRemoved) {
+				t.Errorf("removed = %v, want %v", delta.Removed, tt.wantRemoved)
+			}
+			if got := delta.Resource != nil; got != tt.wantResource {
+				t.Errorf("resource sent = %v, want %v", got, tt.wantResource)
+			}
+			wantEmpty := len(tt.wantUpserted) == 0 && len(tt.wantRemoved) == 0 && !tt.wantResource
+			if got := isEmptyDelta(delta); got != wantEmpty {
+				t.Errorf("isEmptyDelta = %v, want %v", got, wantEmpty)
+			}
+		})
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := &State{
+		Version:   3,
+		Resource:  resource(50*gib, 2),
+		Instances: []*agent.Instance{instance("a", "Running"), instance("b", "Stopped"), instance("c", "Running")},
+	}
+
+	tests := []struct {
+		delta        *StateDelta
+		wantResource *agent.Resource
+		name         string
+		want         []string
+	}{
+		{
+			name:         "empty",
+			delta:        &StateDelta{BaseVersion: 3, Version: 4},
+			want:         []string{"a=Running", "b=Stopped", "c=Running"},
+			wantResource: base.Resource,
+		},
+		{
+			name: "upsert keeps order and appends",
+			delta: &StateDelta{BaseVersion: 3, Version: 4, Upserted: []*agent.Instance{
+				instance("d", "Starting"), instance("b", "Running"),
+			}},
+			want:         []string{"a=Running", "b=Running", "c=Running", "d=Starting"},
+			wantResource: base.Resource,
+		},
+		{
+			name:         "remove",
+			delta:        &StateDelta{BaseVersion: 3, Version: 4, Removed: []string{"a", "c"}},
+			want:         []string{"b=Stopped"},
+			wantResource: base.Resource,
+		},
+		{
+			name:         "resource",
+			delta:        &StateDelta{BaseVersion: 3, Version: 4, Resource: resource(10*gib, 6)},
+			want:         []string{"a=Running", "b=Stopped", "c=Running"},
+			wantResource: resource(10*gib, 6),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := applyDelta(base, tt.delta)
+
+			if state.Version != 4 {
+				t.Errorf("version = %d, want 4", state.Version)
+			}
+			if got := names(state.Instances); !slices.Equal(got, tt.want) {
+				t.Errorf("instances = %v, want %v", got, tt.want)
+			}
+			if !proto.Equal(state.Resource, tt.wantResource) {
+				t.Errorf("resource = %v, want %v", state.Resource, tt.wantResource)
+			}
+			if len(base.Instances) != 3 {
+				t.Errorf("base state changed: %v", names(base.Instances))
+			}
+		})
+	}
+}
+
+// TestDiffApplyRoundTrip checks applying the diff of two states on the first
+// yields the second.
+func TestDiffApplyRoundTrip(t *testing.T) {
+	prev := &State{
+		Version:   1,
+		Resource:  resource(50*gib, 2),
+		Instances: []*agent.Instance{instance("a", "Running"), instance("b", "Stopped")},
+	}
+	next := &State{
+		Version:   2,
+		Resource:  resource(20*gib, 4),
+		Instances: []*agent.Instance{instance("b", "Running"), instance("c", "Running")},
+	}
+
+	got := applyDelta(prev, diffState(prev, next))
+	if !proto.Equal(got, next) {
+		t.Errorf("applyDelta(diffState) = %v, want %v", got, next)
+	}
+}
@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/erayarslan/multiverse/agent"
+	"github.com/erayarslan/multiverse/common"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const eventBufferSize = 64
+
+type subscriber struct {
+	ch     chan *common.Event
+	onDrop func(event *common.Event)
+}
+
+type broadcaster struct {
+	subscribers map[int]*subscriber
+	next        int
+	mu          sync.Mutex
+}
+
+// subscribe registers a buffered channel of events, onDrop when set being
+// called with events the subscriber is too slow to take.
+func (b *broadcaster) subscribe(onDrop func(event *common.Event)) (<-chan *common.Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	ch := make(chan *common.Event, eventBufferSize)
+	b.subscribers[id] = &subscriber{ch: ch, onDrop: onDrop}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[id]; ok {
+			delete(b.subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+func (b *broadcaster) publish(events ...*common.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, event := range events {
+		for _, sub := range b.subscribers {
+			select {
+			case sub.ch <- event:
+			default:
+				log.Printf("dropped %s event for slow subscriber", event.Type)
+				if sub.onDrop != nil {
+					sub.onDrop(event)
+				}
+			}
+		}
+	}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{
+		subscribers: make(map[int]*subscriber),
+	}
+}
+
+func NewEvent(eventType common.EventType, nodeName string, instanceName string) *common.Event {
+	return &common.Event{
+		Type:         eventType,
+		Time:         timestamppb.Now(),
+		NodeName:     nodeName,
+		InstanceName: instanceName,
+	}
+}
+
+func newChangeEvent(eventType common.EventType, nodeName string, instanceName string, oldValue string, newValue string) *common.Event {
+	event := NewEvent(eventType, nodeName, instanceName)
+	event.OldValue = oldValue
+	event.NewValue = newValue
+	return event
+}
+
+func diffInstances(nodeName string, oldInstances []*agent.Instance, newInstances []*agent.Instance) []*common.Event {
+	events := make([]*common.Event, 0)
+
+	old := make(map[string]*agent.Instance, len(oldInstances))
+	for _, instance := range oldInstances {
+		old[instance.Name] = instance
+	}
+
+	for _, instance := range newInstances {
+		prev, ok := old[instance.Name]
+		if !ok {
+			events = append(events, newChangeEvent(common.EventType_INSTANCE_APPEARED,
+				nodeName, instance.Name, "", instance.State))
+			continue
+		}
+		delete(old, instance.Name)
+
+		if prev.State != instance.State {
+			events = append(events, newChangeEvent(common.EventType_INSTANCE_STATE_CHANGED,
+				nodeName, instance.Name, prev.State, instance.State))
+		}
+		if !slices.Equal(prev.Ipv4, instance.Ipv4) {
+			events = append(events, newChangeEvent(common.EventType_INSTANCE_IP_CHANGED,
+				nodeName, instance.Name, strings.Join(prev.Ipv4, ","), strings.Join(instance.Ipv4, ",")))
+		}
+	}
+
+	for _, instance := range oldInstances {
+		if _, ok := old[instance.Name]; ok {
+			events = append(events, newChangeEvent(common.EventType_INSTANCE_DISAPPEARED,
+				nodeName, instance.Name, instance.State, ""))
+		}
+	}
+
+	return events
+}
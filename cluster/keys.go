@@ -0,0 +1,138 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	goSsh "golang.org/x/crypto/ssh"
+)
+
+var keyNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// UserKey is a public key registered by a user, going into the authorized
+// keys of instances under its name.
+type UserKey struct {
+	Added       time.Time `json:"added"`
+	Name        string    `json:"name"`
+	PublicKey   string    `json:"publicKey"`
+	Fingerprint string    `json:"fingerprint"`
+}
+
+// NewUserKey parses an authorized_keys line, the key comment naming it unless
+// name is given.
+func NewUserKey(name string, authorizedKey string) (*UserKey, error) {
+	key, comment, _, _, err := goSsh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if name == "" {
+		name = comment
+	}
+	if !keyNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid key name %q: must be letters, digits and ._@-", name)
+	}
+	return &UserKey{
+		Name:        name,
+		PublicKey:   strings.TrimSpace(string(goSsh.MarshalAuthorizedKey(key))),
+		Fingerprint: goSsh.FingerprintSHA256(key),
+		Added:       time.Now(),
+	}, nil
+}
+
+// AuthorizedKey is the authorized_keys line of the key.
+func (k *UserKey) AuthorizedKey() string {
+	return k.PublicKey + " " + k.Name
+}
+
+func (s *server) UserKeys() []*UserKey {
+	s.keysMu.RLock()
+	defer s.keysMu.RUnlock()
+
+	keys := make([]*UserKey, 0, len(s.userKeys))
+	for _, key := range s.userKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+func (s *server) AddUserKey(key *UserKey) error {
+	return s.updateUserKeys(func(keys map[string]*UserKey) error {
+		// replacing a key would leave the old one in authorized keys of
+		// instances, so it has to be removed and revoked first
+		if _, ok := keys[key.Name]; ok {
+			return fmt.Errorf("key %s is already registered, remove it first", key.Name)
+		}
+		for _, k := range keys {
+			if k.PublicKey == key.PublicKey {
+				return fmt.Errorf("key is already registered as %s", k.Name)
+			}
+		}
+		keys[key.Name] = key
+		return nil
+	})
+}
+
+func (s *server) RemoveUserKey(name string) (*UserKey, error) {
+	var removed *UserKey
+	err := s.updateUserKeys(func(keys map[string]*UserKey) error {
+		key, ok := keys[name]
+		if !ok {
+			return fmt.Errorf("key not found: %s", name)
+		}
+		removed = key
+		delete(keys, name)
+		return nil
+	})
+	return removed, err
+}
+
+// updateUserKeys changes the registered keys, through raft when masters
+// replicate so every master commits them.
+func (s *server) updateUserKeys(update func(keys map[string]*UserKey) error) error {
+	s.specMu.Lock()
+	defer s.specMu.Unlock()
+
+	s.keysMu.RLock()
+	keys := maps.Clone(s.userKeys)
+	s.keysMu.RUnlock()
+
+	if err := update(keys); err != nil {
+		return err
+	}
+	if s.raft == nil {
+		return s.commitUserKeys(keys)
+	}
+
+	data, err := json.Marshal(&specCommand{Kind: keysCommand, Keys: keys})
+	if err != nil {
+		return err
+	}
+	future := s.raft.Apply(data, raftApplyTimeout)
+	if err = future.Error(); err != nil {
+		return fmt.Errorf("failed to replicate keys: %w", err)
+	}
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (s *server) commitUserKeys(keys map[string]*UserKey) error {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+
+	if keys == nil {
+		keys = make(map[string]*UserKey)
+	}
+	s.userKeys = keys
+	if err := s.store.saveUserKeys(keys); err != nil {
+		return fmt.Errorf("failed to persist keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import "testing"
+
+const (
+	aliceKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAICtt3veh3QtgEAtbYX62u/GAurNt946t/IXXhZkB+dK1 alice"
+	bobKey   = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGlA+03f/O12pJfch6+FhppxI7B4Rvb2mjZPtrgb1KZH bob"
+)
+
+func TestAddUserKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		keyName string
+		wantErr bool
+	}{
+		{name: "new key", key: bobKey},
+		{name: "same name", key: bobKey, keyName: "alice", wantErr: true},
+		{name: "same public key", key: aliceKey, keyName: "carol", wantErr: true},
+		{name: "same key again", key: aliceKey, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &server{store: newStore(t.TempDir()), userKeys: map[string]*UserKey{}}
+			alice, err := NewUserKey("", aliceKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = s.AddUserKey(alice); err != nil {
+				t.Fatal(err)
+			}
+
+			key, err := NewUserKey(tt.keyName, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.AddUserKey(key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddUserKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			keys := s.UserKeys()
+			wantKeys := 2
+			if tt.wantErr {
+				wantKeys = 1
+			}
+			if len(keys) != wantKeys || keys[0].Fingerprint != alice.Fingerprint {
+				t.Errorf("keys = %v, want alice kept and %d keys", keys, wantKeys)
+			}
+		})
+	}
+}
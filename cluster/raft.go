@@ -0,0 +1,212 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+)
+
+const (
+	raftApplyTimeout      = 10 * time.Second
+	raftTransportTimeout  = 10 * time.Second
+	raftTransportMaxPool  = 3
+	raftRetainedSnapshots = 2
+
+	keysCommand = "keys"
+
+	// snapshotFormat marks snapshots holding keys next to node specs, older
+	// ones being the node specs alone.
+	snapshotFormat = 2
+)
+
+// Raft replicates node specs and user keys between masters, Addr doubling as the server id.
+// Only the leader takes workers and serves the api.
+type Raft struct {
+	Addr  string
+	Peers []string
+}
+
+// specCommand changes the spec of a node, or replaces the user keys when its
+// kind is keys.
+type specCommand struct {
+	Spec *nodeSpecRecord     `json:"spec,omitempty"`
+	Keys map[string]*UserKey `json:"keys,omitempty"`
+	Node string              `json:"node,omitempty"`
+	Kind string              `json:"kind,omitempty"`
+}
+
+type snapshot struct {
+	Keys   map[string]*UserKey `json:"keys"`
+	Nodes  json.RawMessage     `json:"nodes"`
+	Format int                 `json:"format"`
+}
+
+// specFSM applies replicated node spec and key changes on every master.
+type specFSM struct {
+	s *server
+}
+
+func (f *specFSM) Apply(entry *raft.Log) any {
+	var cmd specCommand
+	if err := json.Unmarshal(entry.Data, &cmd); err != nil {
+		return err
+	}
+	if cmd.Kind == keysCommand {
+		return f.s.commitUserKeys(cmd.Keys)
+	}
+	next, err := cmd.Spec.spec()
+	if err != nil {
+		return err
+	}
+	return f.s.commitNodeSpec(cmd.Node, func(spec *NodeSpec) error {
+		*spec = *next
+		return nil
+	})
+}
+
+func (f *specFSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.s.workersMu.RLock()
+	nodes, err := encodeNodeSpecs(f.s.nodeSpecs)
+	f.s.workersMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	f.s.keysMu.RLock()
+	bytes, err := json.Marshal(&snapshot{Format: snapshotFormat, Nodes: nodes, Keys: f.s.userKeys})
+	f.s.keysMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return specSnapshot(bytes), nil
+}
+
+func (f *specFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	bytes, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err = json.Unmarshal(bytes, &snap); err != nil || snap.Format != snapshotFormat {
+		snap = snapshot{Nodes: bytes}
+	}
+	nodes, err := decodeNodeSpecs(snap.Nodes)
+	if err != nil {
+		return err
+	}
+	if err = f.s.restoreNodeSpecs(nodes); err != nil {
+		return err
+	}
+	return f.s.commitUserKeys(snap.Keys)
+}
+
+type specSnapshot []byte
+
+func (s specSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s); err != nil {
+		_ = sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s specSnapshot) Release() {}
+
+// samePeers reports a raft configuration differing from the peers a master
+// was started with.
+func samePeers(current []raft.Server, peers []raft.Server) error {
+	ids := func(servers []raft.Server) []string {
+		names := make([]string, 0, len(servers))
+		for _, server := range servers {
+			names = append(names, string(server.ID))
+		}
+		slices.Sort(names)
+		return names
+	}
+	if have, want := ids(current), ids(peers); !slices.Equal(have, want) {
+		return fmt.Errorf("raft-peers %s differ from the raft configuration %s in data-dir, every master needs the same list",
+			strings.Join(want, ","), strings.Join(have, ","))
+	}
+	return nil
+}
+
+// newRaft starts the raft server of a master, bootstrapping the cluster from
+// peers on first start. Every master has to bootstrap with the same complete
+// list of peers, later starts check the list against the raft configuration.
+func newRaft(cfg *Raft, dataDir string, fsm raft.FSM, notify chan bool) (*raft.Raft, error) {
+	conf := raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(cfg.Addr)
+	conf.NotifyCh = notify
+	conf.Logger = hclog.New(&hclog.LoggerOptions{Name: "raft", Level: hclog.Warn, Output: log.Writer()})
+
+	dir := filepath.Join(dataDir, "raft")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	boltStore, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft log: %w", err)
+	}
+	snapshots, err := raft.NewFileSnapshotStore(dir, raftRetainedSnapshots, log.Writer())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft snapshots: %w", err)
+	}
+
+	advertise, err := net.ResolveTCPAddr("tcp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := raft.NewTCPTransport(cfg.Addr, advertise, raftTransportMaxPool, raftTransportTimeout, log.Writer())
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for raft: %w", err)
+	}
+
+	hasState, err := raft.HasExistingState(boltStore, boltStore, snapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := raft.NewRaft(conf, fsm, boltStore, boltStore, snapshots, transport)
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]raft.Server, 0, len(cfg.Peers))
+	for _, peer := range cfg.Peers {
+		servers = append(servers, raft.Server{ID: raft.ServerID(peer), Address: raft.ServerAddress(peer)})
+	}
+
+	if hasState {
+		// masters never join or leave after bootstrap, so a differing list
+		// means this master was started with another cluster in mind
+		future := r.GetConfiguration()
+		if err = future.Error(); err != nil {
+			return nil, err
+		}
+		if err = samePeers(future.Configuration().Servers, servers); err != nil {
+			_ = r.Shutdown().Error()
+			return nil, err
+		}
+		return r, nil
+	}
+
+	err = r.BootstrapCluster(raft.Configuration{Servers: servers}).Error()
+	if err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+		return nil, fmt.Errorf("failed to bootstrap raft: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,154 @@
+package cluster
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erayarslan/multiverse/common"
+
+	"github.com/hashicorp/raft"
+)
+
+const electionTimeout = 15 * time.Second
+
+func servers(ids ...string) []raft.Server {
+	s := make([]raft.Server, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, raft.Server{ID: raft.ServerID(id), Address: raft.ServerAddress(id)})
+	}
+	return s
+}
+
+func TestSamePeers(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []raft.Server
+		peers   []raft.Server
+		wantErr bool
+	}{
+		{name: "same", current: servers("a:1", "b:1", "c:1"), peers: servers("a:1", "b:1", "c:1")},
+		{name: "other order", current: servers("a:1", "b:1", "c:1"), peers: servers("c:1", "a:1", "b:1")},
+		{name: "missing peer", current: servers("a:1", "b:1", "c:1"), peers: servers("a:1", "b:1"), wantErr: true},
+		{name: "extra peer", current: servers("a:1", "b:1"), peers: servers("a:1", "b:1", "c:1"), wantErr: true},
+		{name: "other peer", current: servers("a:1", "b:1"), peers: servers("a:1", "d:1"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := samePeers(tt.current, tt.peers); (err != nil) != tt.wantErr {
+				t.Errorf("samePeers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+// waitLeader waits until exactly one of servers leads.
+func waitLeader(t *testing.T, servers []*server) *server {
+	t.Helper()
+	deadline := time.Now().Add(electionTimeout)
+	for time.Now().Before(deadline) {
+		var leaders []*server
+		for _, s := range servers {
+			if s.IsLeader() {
+				leaders = append(leaders, s)
+			}
+		}
+		if len(leaders) == 1 {
+			return leaders[0]
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("no single leader elected within %v", electionTimeout)
+	return nil
+}
+
+// waitSpec waits until the spec of node on s satisfies ok, entries committed
+// before an election being applied by the new leader after it leads.
+func waitSpec(s *server, node string, ok func(spec *NodeSpec) bool) bool {
+	deadline := time.Now().Add(electionTimeout)
+	for time.Now().Before(deadline) {
+		s.workersMu.RLock()
+		spec, found := s.nodeSpecs[node]
+		done := found && ok(spec)
+		s.workersMu.RUnlock()
+		if done {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func startMasters(t *testing.T, n int) []*server {
+	t.Helper()
+	peers := make([]string, 0, n)
+	for range n {
+		peers = append(peers, freeAddr(t))
+	}
+
+	masters := make([]*server, 0, n)
+	for _, peer := range peers {
+		s, err := NewServer("127.0.0.1:0", t.TempDir(), &Raft{Addr: peer, Peers: peers})
+		if err != nil {
+			t.Fatalf("NewServer(%s) error = %v", peer, err)
+		}
+		masters = append(masters, s.(*server))
+	}
+	return masters
+}
+
+func TestRaftFailover(t *testing.T) {
+	if testing.Short() {
+		t.Skip("elects raft leaders over localhost")
+	}
+
+	masters := startMasters(t, 3)
+	t.Cleanup(func() {
+		for _, s := range masters {
+			s.GracefulStop(time.Second)
+		}
+	})
+
+	leader := waitLeader(t, masters)
+	if err := leader.Taint("node", &common.Taint{Key: "gpu", Effect: common.TaintEffectNoSchedule}); err != nil {
+		t.Fatalf("Taint() error = %v", err)
+	}
+
+	// the leader dies without handing leadership over
+	if err := leader.raft.Shutdown().Error(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	rest := make([]*server, 0, len(masters)-1)
+	for _, s := range masters {
+		if s != leader {
+			rest = append(rest, s)
+		}
+	}
+	next := waitLeader(t, rest)
+
+	tainted := waitSpec(next, "node", func(spec *NodeSpec) bool {
+		return len(spec.Taints) == 1 && spec.Taints[0].Key == "gpu"
+	})
+	if !tainted {
+		t.Errorf("gpu taint not replicated to new leader")
+	}
+	// a quorum of two keeps committing without the stopped master
+	if err := next.Cordon("node", true); err != nil {
+		t.Errorf("Cordon() on new leader error = %v", err)
+	}
+	if !waitSpec(next, "node", func(spec *NodeSpec) bool { return spec.Cordoned }) {
+		t.Errorf("cordon not committed on new leader")
+	}
+}
@@ -1,32 +1,47 @@
 package cluster
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/erayarslan/multiverse/agent"
 	"github.com/erayarslan/multiverse/common"
+	"github.com/erayarslan/multiverse/metrics"
 
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 type WorkerInfo struct {
 	AgentClient agent.Client
-	IPPort      string
 	Stream      grpc.BidiStreamingServer[SyncRequest, SyncReply]
 	State       *State
 	LastSync    *timestamppb.Timestamp
+	Labels      map[string]string
+	tunnelLost  chan struct{}
+	IPPort      string
 	NodeName    string
 	UUID        string
+	Taints      []*common.Taint
+	Version     common.PeerVersion
+	Cordoned    bool
+	Ready       bool
 }
 
 type server struct {
@@ -34,40 +49,287 @@ type server struct {
 	listener      net.Listener
 	workerInfoMap map[string]*WorkerInfo
 	grpcServer    *grpc.Server
+	nodeSpecs     map[string]*NodeSpec
+	userKeys      map[string]*UserKey
+	store         *store
+	events        *broadcaster
+	raft          *raft.Raft
+	closing       chan struct{}
+	stepDown      chan struct{}
 	workersMu     sync.RWMutex
+	leaderMu      sync.RWMutex
+	keysMu        sync.RWMutex
+	specMu        sync.Mutex
+	leader        bool
 }
 
 type Server interface {
 	IterateWorkers(callback func(info *WorkerInfo) bool)
+	Taint(nodeName string, taint *common.Taint) error
+	Untaint(nodeName string, key string) error
+	Cordon(nodeName string, cordoned bool) error
+	Subscribe() (<-chan *common.Event, func())
+	SubscribeDropping(onDrop func(event *common.Event)) (<-chan *common.Event, func())
+	Publish(events ...*common.Event)
 	Serve() error
+	GracefulStop(timeout time.Duration)
+	IsLeader() bool
+	UserKeys() []*UserKey
+	AddUserKey(key *UserKey) error
+	RemoveUserKey(name string) (*UserKey, error)
+}
+
+var errLeaving = errors.New("worker is leaving")
+
+// IsLeader reports whether this master leads, a master without raft always
+// does.
+func (s *server) IsLeader() bool {
+	s.leaderMu.RLock()
+	defer s.leaderMu.RUnlock()
+	return s.leader
+}
+
+// leadership returns whether this master leads and a channel closed once it
+// stops leading.
+func (s *server) leadership() (bool, <-chan struct{}) {
+	s.leaderMu.RLock()
+	defer s.leaderMu.RUnlock()
+	return s.leader, s.stepDown
+}
+
+func (s *server) watchLeadership(notify <-chan bool) {
+	for leader := range notify {
+		s.leaderMu.Lock()
+		if leader && !s.leader {
+			log.Printf("became leader")
+			s.stepDown = make(chan struct{})
+		}
+		if !leader && s.leader {
+			log.Printf("lost leadership, dropping workers")
+			close(s.stepDown)
+		}
+		s.leader = leader
+		s.leaderMu.Unlock()
+	}
 }
 
 func (s *server) IterateWorkers(callback func(info *WorkerInfo) bool) {
 	s.workersMu.Lock()
 	defer s.workersMu.Unlock()
 	for _, workerInfo := range s.workerInfoMap {
-		if workerInfo.State == nil || !callback(workerInfo) {
+		if workerInfo.State == nil {
+			continue
+		}
+		if !callback(workerInfo) {
 			break
 		}
 	}
 }
 
+func (s *server) nodeSpec(nodeName string) *NodeSpec {
+	spec, ok := s.nodeSpecs[nodeName]
+	if !ok {
+		spec = &NodeSpec{}
+		s.nodeSpecs[nodeName] = spec
+	}
+	return spec
+}
+
+func (s *server) applyNodeSpec(workerInfo *WorkerInfo) {
+	spec := s.nodeSpec(workerInfo.NodeName)
+	workerInfo.Taints = spec.Taints
+	workerInfo.Cordoned = spec.Cordoned
+}
+
+// updateNodeSpec changes the spec of a node, through raft when masters
+// replicate so every master commits it.
+func (s *server) updateNodeSpec(nodeName string, update func(spec *NodeSpec) error) error {
+	if s.raft == nil {
+		return s.commitNodeSpec(nodeName, update)
+	}
+
+	s.specMu.Lock()
+	defer s.specMu.Unlock()
+
+	var spec NodeSpec
+	s.workersMu.RLock()
+	if current, ok := s.nodeSpecs[nodeName]; ok {
+		spec = NodeSpec{Taints: slices.Clone(current.Taints), Cordoned: current.Cordoned}
+	}
+	s.workersMu.RUnlock()
+
+	if err := update(&spec); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&specCommand{Node: nodeName, Spec: newNodeSpecRecord(&spec)})
+	if err != nil {
+		return err
+	}
+	future := s.raft.Apply(data, raftApplyTimeout)
+	if err = future.Error(); err != nil {
+		return fmt.Errorf("failed to replicate node %s: %w", nodeName, err)
+	}
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (s *server) commitNodeSpec(nodeName string, update func(spec *NodeSpec) error) error {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+
+	// update a copy so nodes keep their spec when persisting fails
+	spec := &NodeSpec{}
+	if current, ok := s.nodeSpecs[nodeName]; ok {
+		spec = &NodeSpec{Taints: slices.Clone(current.Taints), Cordoned: current.Cordoned}
+	}
+	if err := update(spec); err != nil {
+		return err
+	}
+
+	nodeSpecs := maps.Clone(s.nodeSpecs)
+	nodeSpecs[nodeName] = spec
+	if err := s.store.save(nodeSpecs); err != nil {
+		return fmt.Errorf("failed to persist node %s: %w", nodeName, err)
+	}
+	s.nodeSpecs = nodeSpecs
+
+	for _, workerInfo := range s.workerInfoMap {
+		if workerInfo.NodeName == nodeName {
+			s.applyNodeSpec(workerInfo)
+		}
+	}
+	return nil
+}
+
+func (s *server) restoreNodeSpecs(nodeSpecs map[string]*NodeSpec) error {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+
+	s.nodeSpecs = nodeSpecs
+	for _, workerInfo := range s.workerInfoMap {
+		s.applyNodeSpec(workerInfo)
+	}
+	return s.store.save(s.nodeSpecs)
+}
+
+func (s *server) Taint(nodeName string, taint *common.Taint) error {
+	// an invalid taint would keep the node specs from loading again
+	if err := common.ValidateTaint(taint); err != nil {
+		return err
+	}
+	return s.updateNodeSpec(nodeName, func(spec *NodeSpec) error {
+		taints := make([]*common.Taint, 0, len(spec.Taints)+1)
+		for _, t := range spec.Taints {
+			if t.Key != taint.Key || t.Effect != taint.Effect {
+				taints = append(taints, t)
+			}
+		}
+		spec.Taints = append(taints, taint)
+		return nil
+	})
+}
+
+func (s *server) Untaint(nodeName string, key string) error {
+	return s.updateNodeSpec(nodeName, func(spec *NodeSpec) error {
+		taints := make([]*common.Taint, 0, len(spec.Taints))
+		for _, t := range spec.Taints {
+			if t.Key != key {
+				taints = append(taints, t)
+			}
+		}
+		if len(taints) == len(spec.Taints) {
+			return fmt.Errorf("taint %s not found on node: %s", key, nodeName)
+		}
+		spec.Taints = taints
+		return nil
+	})
+}
+
+func (s *server) Cordon(nodeName string, cordoned bool) error {
+	return s.updateNodeSpec(nodeName, func(spec *NodeSpec) error {
+		spec.Cordoned = cordoned
+		return nil
+	})
+}
+
+func (s *server) Subscribe() (<-chan *common.Event, func()) {
+	return s.events.subscribe(nil)
+}
+
+// SubscribeDropping subscribes like Subscribe, passing events dropped for
+// being slow to onDrop.
+func (s *server) SubscribeDropping(onDrop func(event *common.Event)) (<-chan *common.Event, func()) {
+	return s.events.subscribe(onDrop)
+}
+
+func (s *server) Publish(events ...*common.Event) {
+	s.events.publish(events...)
+}
+
 func (s *server) addWorkerInfo(uid string, workerInfo *WorkerInfo) {
 	s.workersMu.Lock()
 	defer s.workersMu.Unlock()
+	s.applyNodeSpec(workerInfo)
+	workerInfo.Ready = true
 	s.workerInfoMap[uid] = workerInfo
+	s.events.publish(NewEvent(common.EventType_NODE_JOINED, workerInfo.NodeName, ""))
+}
+
+// updateDelta applies a state delta, reporting a version gap when the worker
+// state master holds is not the base of the delta.
+func (s *server) updateDelta(uid string, delta *StateDelta) (bool, error) {
+	s.workersMu.RLock()
+	workerInfo, ok := s.workerInfoMap[uid]
+	var state *State
+	if ok {
+		state = workerInfo.State
+	}
+	s.workersMu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+	if state == nil || state.Version != delta.BaseVersion {
+		log.Printf("state version gap for node %s, have %d, delta base %d",
+			workerInfo.NodeName, state.GetVersion(), delta.BaseVersion)
+		return true, nil
+	}
+
+	return false, s.updateState(uid, applyDelta(state, delta))
 }
 
 func (s *server) updateState(uid string, state *State) error {
 	s.workersMu.Lock()
 	defer s.workersMu.Unlock()
 	if workerInfo, ok := s.workerInfoMap[uid]; ok {
+		if workerInfo.State != nil {
+			s.events.publish(diffInstances(workerInfo.NodeName, workerInfo.State.Instances, state.Instances)...)
+		}
+		if !workerInfo.Ready {
+			workerInfo.Ready = true
+			s.events.publish(NewEvent(common.EventType_NODE_READY, workerInfo.NodeName, ""))
+		}
 		workerInfo.State = state
 		workerInfo.LastSync = timestamppb.Now()
 	}
 	return nil
 }
 
+func (s *server) updateLabels(uid string, labels map[string]string) {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+	if workerInfo, ok := s.workerInfoMap[uid]; ok {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		workerInfo.Labels = labels
+		log.Printf("labels updated for node: %s", workerInfo.NodeName)
+	}
+}
+
 func (s *server) removeWorkerInfo(uid string) {
 	s.workersMu.Lock()
 	defer s.workersMu.Unlock()
@@ -76,14 +338,55 @@ func (s *server) removeWorkerInfo(uid string) {
 		if err != nil {
 			log.Printf("failed to close agent client of worker: %v", err)
 		}
+		s.events.publish(NewEvent(common.EventType_NODE_LEFT, workerInfo.NodeName, ""))
 	}
 	delete(s.workerInfoMap, uid)
 }
 
+func (s *server) checkReadiness() {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+	for _, workerInfo := range s.workerInfoMap {
+		if workerInfo.Ready && time.Since(workerInfo.LastSync.AsTime()) > common.NotReadyTimeout {
+			workerInfo.Ready = false
+			log.Printf("node not ready: %s", workerInfo.NodeName)
+			s.events.publish(NewEvent(common.EventType_NODE_NOT_READY, workerInfo.NodeName, ""))
+		}
+	}
+}
+
+func (s *server) monitor() {
+	ticker := time.NewTicker(common.NotReadyTimeout / 3)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.checkReadiness()
+	}
+}
+
 func (s *server) Serve() error {
+	go s.monitor()
 	return s.grpcServer.Serve(s.listener)
 }
 
+// GracefulStop hands leadership over, ends the sync streams so workers
+// reconnect to the next master and waits for pending rpcs.
+func (s *server) GracefulStop(timeout time.Duration) {
+	if s.raft != nil && s.IsLeader() {
+		if err := s.raft.LeadershipTransfer().Error(); err != nil {
+			log.Printf("failed to transfer leadership: %v", err)
+		}
+	}
+
+	close(s.closing)
+	common.GracefulStop(s.grpcServer, timeout)
+
+	if s.raft != nil {
+		if err := s.raft.Shutdown().Error(); err != nil {
+			log.Printf("failed to shut down raft: %v", err)
+		}
+	}
+}
+
 func (s *server) Sync(stream grpc.BidiStreamingServer[SyncRequest, SyncReply]) error {
 	id := uuid.Must(uuid.NewRandom()).String()
 	defer log.Printf("client disconnected: %s", id)
@@ -112,49 +415,208 @@ func (s *server) Sync(stream grpc.BidiStreamingServer[SyncRequest, SyncReply]) e
 	}
 	port, _ := strconv.Atoi(agentPort[0])
 
-	host := strings.Split(p.Addr.String(), ":")[0]
-	target := fmt.Sprintf("%s:%d", host, port)
+	labels, err := common.ParseLabels(strings.Join(md.Get("labels"), ","))
+	if err != nil {
+		return err
+	}
 
-	if err := stream.Send(&SyncReply{
-		Uuid: id,
-	}); err != nil {
-		return fmt.Errorf("failed to send join reply on master: %w", err)
+	// master advertises itself even when refusing so the worker can tell why
+	version := common.PeerVersionFromMetadata(md)
+	if err := stream.SendHeader(common.VersionMetadata()); err != nil {
+		return fmt.Errorf("failed to send version header: %w", err)
+	}
+	if err := version.Compatible(); err != nil {
+		log.Printf("refused node name: %s, %v", nodeName[0], err)
+		return status.Errorf(codes.FailedPrecondition, "worker %v", err)
 	}
 
+	leader, stepDown := s.leadership()
+	if !leader {
+		return status.Error(codes.Unavailable, "master is not the leader")
+	}
+
+	host := strings.Split(p.Addr.String(), ":")[0]
+	target := fmt.Sprintf("%s:%d", host, port)
+
+	// dialing back is the fallback until the worker opens its tunnel
 	agentClient, err := agent.NewClient(target)
 	if err != nil {
 		return fmt.Errorf("failed to create multipass client: %w", err)
 	}
 
+	tunnelLost := make(chan struct{})
 	s.addWorkerInfo(id, &WorkerInfo{
 		AgentClient: agentClient,
 		Stream:      stream,
+		tunnelLost:  tunnelLost,
 		NodeName:    nodeName[0],
+		Labels:      labels,
 		UUID:        id,
 		IPPort:      target,
+		Version:     version,
 		LastSync:    timestamppb.Now(),
 	})
 
-	log.Printf("joined node name: %s, uuid: %s", nodeName[0], id)
+	if err := stream.Send(&SyncReply{
+		Uuid: id,
+	}); err != nil {
+		return fmt.Errorf("failed to send join reply on master: %w", err)
+	}
 
-	return common.ListenBidiServer(stream, func(req *SyncRequest) error {
-		return s.updateState(id, req.GetState())
-	})
+	log.Printf("joined node name: %s, uuid: %s, version: %s", nodeName[0], id, version)
+	metrics.SyncStreams.Inc()
+	defer metrics.SyncStreams.Dec()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- common.ListenBidiServer(stream, func(req *SyncRequest) error {
+			if req.GetLeaving() {
+				return errLeaving
+			}
+			if req.GetLabels() != nil {
+				s.updateLabels(id, req.GetLabels().GetItems())
+			}
+			if req.GetDelta() != nil {
+				gap, err := s.updateDelta(id, req.GetDelta())
+				if err != nil || !gap {
+					return err
+				}
+				return stream.Send(&SyncReply{Uuid: id, Resync: true})
+			}
+			if req.GetState() == nil {
+				return nil
+			}
+			return s.updateState(id, req.GetState())
+		})
+	}()
+
+	select {
+	case err = <-done:
+		if errors.Is(err, errLeaving) {
+			log.Printf("node leaving: %s", nodeName[0])
+			return nil
+		}
+		return err
+	case <-s.closing:
+		return nil
+	case <-stepDown:
+		return status.Error(codes.Unavailable, "master lost leadership")
+	case <-tunnelLost:
+		// the worker rejoins and opens a new tunnel
+		return status.Error(codes.Unavailable, "agent tunnel closed")
+	}
+}
+
+// setAgentClient swaps the agent client of a worker, closing the previous one.
+func (s *server) setAgentClient(uid string, agentClient agent.Client) bool {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+	workerInfo, ok := s.workerInfoMap[uid]
+	if !ok {
+		return false
+	}
+	if err := workerInfo.AgentClient.Close(); err != nil {
+		log.Printf("failed to close agent client of worker: %v", err)
+	}
+	workerInfo.AgentClient = agentClient
+	return true
+}
+
+// loseTunnel ends the sync stream of a worker when the tunnel that closed is
+// still its agent client, since the dial back client it replaced is gone.
+func (s *server) loseTunnel(uid string, agentClient agent.Client) {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+	workerInfo, ok := s.workerInfoMap[uid]
+	if !ok || workerInfo.AgentClient != agentClient {
+		return
+	}
+	select {
+	case <-workerInfo.tunnelLost:
+	default:
+		close(workerInfo.tunnelLost)
+	}
+}
+
+// Tunnel serves the agent rpc of a joined worker over the worker's own
+// connection, so master never dials back into the worker.
+func (s *server) Tunnel(stream grpc.BidiStreamingServer[TunnelFrame, TunnelFrame]) error {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	if !ok {
+		return fmt.Errorf("metadata not found in context")
+	}
+
+	uid := md.Get("uuid")
+	if len(uid) == 0 {
+		return fmt.Errorf("uuid not found in context")
+	}
+
+	conn := newTunnelConn(stream, uid[0], nil)
+	agentClient, err := agent.NewTunnelClient(conn)
+	if err != nil {
+		return fmt.Errorf("failed to create tunnel client: %w", err)
+	}
+
+	if !s.setAgentClient(uid[0], agentClient) {
+		_ = agentClient.Close()
+		return fmt.Errorf("worker not found: %s", uid[0])
+	}
+	log.Printf("agent tunnel opened: %s", uid[0])
+	defer log.Printf("agent tunnel closed: %s", uid[0])
+
+	select {
+	case <-conn.Done():
+	case <-stream.Context().Done():
+	case <-s.closing:
+		return nil
+	}
+	s.loseTunnel(uid[0], agentClient)
+	return nil
 }
 
-func NewServer(addr string) (Server, error) {
+// NewServer creates the cluster server of a master, replicating node specs
+// through raft unless raftConfig is nil.
+func NewServer(addr string, dataDir string, raftConfig *Raft) (Server, error) {
+	store := newStore(dataDir)
+	nodeSpecs, err := store.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load nodes: %w", err)
+	}
+	userKeys, err := store.loadUserKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keys: %w", err)
+	}
+
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
-	var opts []grpc.ServerOption
+	opts := metrics.ServerOptions("cluster")
 	grpcServer := grpc.NewServer(opts...)
 	server := &server{
 		workersMu:     sync.RWMutex{},
 		workerInfoMap: map[string]*WorkerInfo{},
+		nodeSpecs:     nodeSpecs,
+		userKeys:      userKeys,
+		store:         store,
+		events:        newBroadcaster(),
+		closing:       make(chan struct{}),
 		listener:      lis,
 		grpcServer:    grpcServer,
+		stepDown:      make(chan struct{}),
+		leader:        raftConfig == nil,
 	}
+
+	if raftConfig != nil {
+		notify := make(chan bool, 1)
+		server.raft, err = newRaft(raftConfig, dataDir, &specFSM{s: server}, notify)
+		if err != nil {
+			_ = lis.Close()
+			return nil, err
+		}
+		go server.watchLeadership(notify)
+	}
+
 	RegisterRpcServer(grpcServer, server)
 	return server, nil
 }
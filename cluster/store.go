@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/erayarslan/multiverse/common"
+)
+
+type NodeSpec struct {
+	Taints   []*common.Taint
+	Cordoned bool
+}
+
+type nodeSpecRecord struct {
+	Taints   []string `json:"taints,omitempty"`
+	Cordoned bool     `json:"cordoned,omitempty"`
+}
+
+func newNodeSpecRecord(spec *NodeSpec) *nodeSpecRecord {
+	record := &nodeSpecRecord{Cordoned: spec.Cordoned}
+	for _, taint := range spec.Taints {
+		record.Taints = append(record.Taints, taint.Format())
+	}
+	return record
+}
+
+func (r *nodeSpecRecord) spec() (*NodeSpec, error) {
+	spec := &NodeSpec{Cordoned: r.Cordoned}
+	for _, t := range r.Taints {
+		taint, err := common.ParseTaint(t)
+		if err != nil {
+			return nil, err
+		}
+		spec.Taints = append(spec.Taints, taint)
+	}
+	return spec, nil
+}
+
+func encodeNodeSpecs(nodes map[string]*NodeSpec) ([]byte, error) {
+	records := make(map[string]*nodeSpecRecord, len(nodes))
+	for nodeName, spec := range nodes {
+		records[nodeName] = newNodeSpecRecord(spec)
+	}
+	return json.MarshalIndent(records, "", "  ")
+}
+
+func decodeNodeSpecs(bytes []byte) (map[string]*NodeSpec, error) {
+	records := make(map[string]*nodeSpecRecord)
+	if err := json.Unmarshal(bytes, &records); err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*NodeSpec, len(records))
+	for nodeName, record := range records {
+		spec, err := record.spec()
+		if err != nil {
+			return nil, err
+		}
+		nodes[nodeName] = spec
+	}
+	return nodes, nil
+}
+
+type store struct {
+	path     string
+	keysPath string
+}
+
+func (s *store) load() (map[string]*NodeSpec, error) {
+	if s.path == "" {
+		return make(map[string]*NodeSpec), nil
+	}
+
+	bytes, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]*NodeSpec), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeNodeSpecs(bytes)
+}
+
+func (s *store) save(nodes map[string]*NodeSpec) error {
+	if s.path == "" {
+		return nil
+	}
+
+	bytes, err := encodeNodeSpecs(nodes)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(s.path, bytes)
+}
+
+func (s *store) loadUserKeys() (map[string]*UserKey, error) {
+	keys := make(map[string]*UserKey)
+	if s.keysPath == "" {
+		return keys, nil
+	}
+
+	bytes, err := os.ReadFile(s.keysPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(bytes, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *store) saveUserKeys(keys map[string]*UserKey) error {
+	if s.keysPath == "" {
+		return nil
+	}
+
+	bytes, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(s.keysPath, bytes)
+}
+
+func writeAtomic(path string, bytes []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, bytes, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func newStore(dataDir string) *store {
+	if dataDir == "" {
+		return &store{}
+	}
+	return &store{path: filepath.Join(dataDir, "nodes.json"), keysPath: filepath.Join(dataDir, "keys.json")}
+}
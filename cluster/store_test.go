@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/erayarslan/multiverse/common"
+)
+
+func TestTaintSurvivesStore(t *testing.T) {
+	tests := []struct {
+		taint   *common.Taint
+		name    string
+		wantErr bool
+	}{
+		{name: "key and effect", taint: &common.Taint{Key: "gpu", Effect: common.TaintEffectNoSchedule}},
+		{name: "value", taint: &common.Taint{Key: "gpu", Value: "a=b", Effect: common.TaintEffectPreferNoSchedule}},
+		{name: "no effect", taint: &common.Taint{Key: "gpu"}, wantErr: true},
+		{name: "unknown effect", taint: &common.Taint{Key: "gpu", Effect: "NoExecute"}, wantErr: true},
+		{name: "empty key", taint: &common.Taint{Effect: common.TaintEffectNoSchedule}, wantErr: true},
+		{name: "colon in key", taint: &common.Taint{Key: "g:pu", Effect: common.TaintEffectNoSchedule}, wantErr: true},
+		{name: "equals in key", taint: &common.Taint{Key: "g=pu", Effect: common.TaintEffectNoSchedule}, wantErr: true},
+		{name: "colon in value", taint: &common.Taint{Key: "gpu", Value: "a:b", Effect: common.TaintEffectNoSchedule}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := &server{store: newStore(dir), nodeSpecs: map[string]*NodeSpec{}, workerInfoMap: map[string]*WorkerInfo{}}
+
+			err := s.Taint("node", tt.taint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Taint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			nodes, err := newStore(dir).load()
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			var taints []*common.Taint
+			if spec, ok := nodes["node"]; ok {
+				taints = spec.Taints
+			}
+			if tt.wantErr {
+				if len(taints) != 0 {
+					t.Errorf("stored taints = %v, want none", taints)
+				}
+				return
+			}
+			if len(taints) != 1 || taints[0].Key != tt.taint.Key || taints[0].Value != tt.taint.Value ||
+				taints[0].Effect != tt.taint.Effect {
+				t.Errorf("stored taints = %v, want %v", taints, tt.taint)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxFrameSize keeps tunnel frames well below the default grpc message limit.
+const maxFrameSize = 32 * 1024
+
+type frameStream interface {
+	Send(*TunnelFrame) error
+	Recv() (*TunnelFrame, error)
+	Context() context.Context
+}
+
+type tunnelAddr string
+
+func (a tunnelAddr) Network() string { return "tunnel" }
+func (a tunnelAddr) String() string  { return string(a) }
+
+// tunnelConn carries a byte stream over tunnel frames so grpc can run on top
+// of a worker's sync connection.
+type tunnelConn struct {
+	stream  frameStream
+	onClose func()
+	closed  chan struct{}
+	addr    tunnelAddr
+	buf     []byte
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+	once    sync.Once
+}
+
+func newTunnelConn(stream frameStream, addr string, onClose func()) *tunnelConn {
+	return &tunnelConn{
+		stream:  stream,
+		onClose: onClose,
+		closed:  make(chan struct{}),
+		addr:    tunnelAddr(addr),
+	}
+}
+
+func (c *tunnelConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for len(c.buf) == 0 {
+		frame, err := c.stream.Recv()
+		if err != nil {
+			_ = c.Close()
+			return 0, io.EOF
+		}
+		c.buf = frame.GetData()
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *tunnelConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	written := 0
+	for written < len(p) {
+		select {
+		case <-c.closed:
+			return written, net.ErrClosed
+		default:
+		}
+
+		end := min(written+maxFrameSize, len(p))
+		if err := c.stream.Send(&TunnelFrame{Data: p[written:end]}); err != nil {
+			_ = c.Close()
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+func (c *tunnelConn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return nil
+}
+
+// Done is closed once either side closes the tunnel.
+func (c *tunnelConn) Done() <-chan struct{} {
+	return c.closed
+}
+
+func (c *tunnelConn) LocalAddr() net.Addr  { return c.addr }
+func (c *tunnelConn) RemoteAddr() net.Addr { return c.addr }
+
+// deadlines are not supported, the stream context bounds the tunnel instead
+func (c *tunnelConn) SetDeadline(time.Time) error      { return nil }
+func (c *tunnelConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *tunnelConn) SetWriteDeadline(time.Time) error { return nil }
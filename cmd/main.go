@@ -6,11 +6,18 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/erayarslan/multiverse/cli"
+	"github.com/erayarslan/multiverse/common"
 	"github.com/erayarslan/multiverse/config"
+	"github.com/erayarslan/multiverse/metrics"
 	"github.com/erayarslan/multiverse/role"
 )
 
 func main() {
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Main(os.Args[1:]))
+	}
+
 	defer log.Printf("Multiverse is shutting down")
 
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
@@ -39,6 +46,19 @@ func main() {
 		return
 	}
 
+	if cfg.IsMaster || cfg.IsWorker {
+		log.Printf("version: %s, protocol: %d", common.Version, common.ProtocolVersion)
+	}
+
+	if cfg.MetricsAddr != "" && (cfg.IsMaster || cfg.IsWorker) {
+		log.Printf("metrics addr: %s", cfg.MetricsAddr)
+		go func() {
+			if err := metrics.Serve(cfg.MetricsAddr); err != nil {
+				log.Fatalf("error while serving metrics: %v", err)
+			}
+		}()
+	}
+
 	defer func() {
 		for _, r := range roles {
 			if err := r.GracefulShutdown(); err != nil {
@@ -54,8 +74,50 @@ func main() {
 		}
 	}
 
-	select {
-	case <-doneCh:
-	case <-closeCh:
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-doneCh:
+			return
+		case <-closeCh:
+			return
+		case <-reloadCh:
+			reload(cfg, roles)
+		}
+	}
+}
+
+// reload applies the changeable settings of a freshly read config to the
+// roles and reports every changed setting no role applied, failed ones too,
+// as needing a restart. Changes are always compared with the startup config,
+// so they are reported until the daemon restarts.
+func reload(cfg *config.Config, roles []role.Role) {
+	log.Printf("reloading config")
+
+	next, err := cfg.Reload()
+	if err != nil {
+		log.Printf("error while reloading config, keeping current config: %v", err)
+		return
+	}
+
+	applied := make(map[string]bool)
+	for _, r := range roles {
+		names, err := r.Reload(next)
+		if err != nil {
+			log.Printf("error while reloading role: %v", err)
+		}
+		for _, name := range names {
+			applied[name] = true
+		}
+	}
+
+	for _, name := range cfg.Changed(next) {
+		if applied[name] {
+			log.Printf("config %s reloaded", name)
+		} else {
+			log.Printf("config %s changed, restart required to apply", name)
+		}
 	}
 }
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/erayarslan/multiverse/gateway"
+)
+
+func main() {
+	doc, err := gateway.OpenAPI()
+	if err != nil {
+		log.Fatalf("error while generating openapi document: %v", err)
+	}
+
+	fmt.Println(string(doc))
+}
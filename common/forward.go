@@ -0,0 +1,96 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"slices"
+)
+
+// maxForwardFrame keeps forward frames well below the default grpc message limit.
+const maxForwardFrame = 32 * 1024
+
+type forwardStream interface {
+	Send(*ForwardFrame) error
+	Recv() (*ForwardFrame, error)
+}
+
+type sendCloser interface {
+	CloseSend() error
+}
+
+type writeCloser interface {
+	CloseWrite() error
+}
+
+// PipeForward sends what r reads as frames on stream and writes received
+// frames to w until both directions end. Once r ends a client stream is
+// half-closed and the pipe waits for the server to end it, while a server
+// stream half-closed by its client half-closes w and waits for r to end. The
+// first error ends the pipe.
+func PipeForward(stream forwardStream, r io.Reader, w io.Writer) error {
+	closer, opened := stream.(sendCloser)
+	sent := make(chan error, 1)
+	received := make(chan error, 1)
+
+	go func() {
+		buf := make([]byte, maxForwardFrame)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				if sendErr := stream.Send(&ForwardFrame{Data: slices.Clone(buf[:n])}); sendErr != nil {
+					sent <- sendErr
+					return
+				}
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				sent <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				received <- err
+				return
+			}
+			if _, err = w.Write(frame.GetData()); err != nil {
+				received <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case err := <-sent:
+			if err != nil || !opened {
+				return err
+			}
+			if err = closer.CloseSend(); err != nil {
+				return err
+			}
+			sent = nil
+		case err := <-received:
+			if err != nil || opened {
+				return err
+			}
+			wc, ok := w.(writeCloser)
+			if !ok {
+				return nil
+			}
+			if err = wc.CloseWrite(); err != nil {
+				return err
+			}
+			received = nil
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeForwardStream receives the frames of in until it is closed, recording
+// what is sent.
+type fakeForwardStream struct {
+	in   chan *ForwardFrame
+	sent bytes.Buffer
+	mu   sync.Mutex
+}
+
+func (s *fakeForwardStream) Send(frame *ForwardFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent.Write(frame.GetData())
+	return nil
+}
+
+func (s *fakeForwardStream) Recv() (*ForwardFrame, error) {
+	frame, ok := <-s.in
+	if !ok {
+		return nil, io.EOF
+	}
+	return frame, nil
+}
+
+func (s *fakeForwardStream) sentString() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent.String()
+}
+
+// fakeClientStream is the side opening the stream, its peer answering once
+// it half-closes.
+type fakeClientStream struct {
+	*fakeForwardStream
+	closed chan struct{}
+}
+
+func (s *fakeClientStream) CloseSend() error {
+	close(s.closed)
+	return nil
+}
+
+// halfCloser is a connection whose peer answers once it is half-closed.
+type halfCloser struct {
+	closeWrite func()
+	bytes.Buffer
+}
+
+func (c *halfCloser) CloseWrite() error {
+	c.closeWrite()
+	return nil
+}
+
+func TestPipeForwardClientWaitsForServer(t *testing.T) {
+	stream := &fakeClientStream{
+		fakeForwardStream: &fakeForwardStream{in: make(chan *ForwardFrame, 2)},
+		closed:            make(chan struct{}),
+	}
+	stream.in <- &ForwardFrame{Data: []byte("hello ")}
+	go func() {
+		<-stream.closed
+		stream.in <- &ForwardFrame{Data: []byte("back")}
+		close(stream.in)
+	}()
+
+	var w bytes.Buffer
+	if err := PipeForward(stream, strings.NewReader("request"), &w); err != nil {
+		t.Fatalf("PipeForward() error = %v", err)
+	}
+	if got := stream.sentString(); got != "request" {
+		t.Errorf("sent = %q, want %q", got, "request")
+	}
+	if got := w.String(); got != "hello back" {
+		t.Errorf("received = %q, want %q", got, "hello back")
+	}
+}
+
+func TestPipeForwardServerHalfClosesWriter(t *testing.T) {
+	stream := &fakeForwardStream{in: make(chan *ForwardFrame, 1)}
+	stream.in <- &ForwardFrame{Data: []byte("request")}
+	close(stream.in)
+
+	r, pw := io.Pipe()
+	conn := &halfCloser{closeWrite: func() {
+		go func() {
+			_, _ = pw.Write([]byte("answer"))
+			_ = pw.Close()
+		}()
+	}}
+
+	if err := PipeForward(stream, r, conn); err != nil {
+		t.Fatalf("PipeForward() error = %v", err)
+	}
+	if got := conn.String(); got != "request" {
+		t.Errorf("written = %q, want %q", got, "request")
+	}
+	if got := stream.sentString(); got != "answer" {
+		t.Errorf("sent = %q, want %q", got, "answer")
+	}
+}
+
+func TestPipeForwardServerEndsWithReader(t *testing.T) {
+	stream := &fakeForwardStream{in: make(chan *ForwardFrame)}
+	defer close(stream.in)
+
+	if err := PipeForward(stream, strings.NewReader("bye"), io.Discard); err != nil {
+		t.Fatalf("PipeForward() error = %v", err)
+	}
+	if got := stream.sentString(); got != "bye" {
+		t.Errorf("sent = %q, want %q", got, "bye")
+	}
+}
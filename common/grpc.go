@@ -3,10 +3,53 @@ package common
 import (
 	"io"
 	"log"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 )
 
+const shutdownNotice = "\r\nmultiverse is shutting down, closing shell\r\n"
+
+// NotReadyTimeout is how long master waits for a worker state sync before
+// marking the node not ready.
+const NotReadyTimeout = 30 * time.Second
+
+// ShutdownNotice is sent to shell users before their session is closed.
+func ShutdownNotice() *ShellReply {
+	return &ShellReply{ErrBuffer: []byte(shutdownNotice)}
+}
+
+// ParseAddrs splits a comma separated list of addrs, the first one being
+// the addr a master listens on.
+func ParseAddrs(addrs string) []string {
+	parsed := make([]string, 0)
+	for _, addr := range strings.Split(addrs, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			parsed = append(parsed, addr)
+		}
+	}
+	return parsed
+}
+
+// GracefulStop waits for pending rpcs to finish and stops the server by force
+// once timeout passes.
+func GracefulStop(grpcServer *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Printf("graceful stop timed out after %v, stopping", timeout)
+		grpcServer.Stop()
+		<-done
+	}
+}
+
 func ExecuteOnceWithBidiClient[Req any, Res any](stream grpc.BidiStreamingClient[Req, Res], req *Req) (res *Res, err error) {
 	err = stream.Send(req)
 	if err != nil {
@@ -26,6 +69,22 @@ func ExecuteOnceWithBidiClient[Req any, Res any](stream grpc.BidiStreamingClient
 	return in, nil
 }
 
+func ExecuteWithBidiClient[Req any, Res any](stream grpc.BidiStreamingClient[Req, Res], req *Req) (res *Res, err error) {
+	if err = stream.Send(req); err != nil {
+		return nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	err = ListenBidiClient(stream, func(in *Res) error {
+		res = in
+		return nil
+	})
+
+	return res, err
+}
+
 func ListenBidiServer[Req any, Res any](stream grpc.BidiStreamingServer[Req, Res], f func(req *Req) error) error {
 	for {
 		req, err := stream.Recv()
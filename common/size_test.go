@@ -0,0 +1,37 @@
+package common
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    uint64
+		wantErr bool
+	}{
+		{size: "1024", want: 1024},
+		{size: "2K", want: 2 << 10},
+		{size: "512M", want: 512 << 20},
+		{size: "512MB", want: 512 << 20},
+		{size: "4G", want: 4 << 30},
+		{size: "4GiB", want: 4 << 30},
+		{size: " 1.5g ", want: 3 << 29},
+		{size: "1T", want: 1 << 40},
+		{size: "0", want: 0},
+		{size: "", wantErr: true},
+		{size: "G", wantErr: true},
+		{size: "10Q", wantErr: true},
+		{size: "-1G", wantErr: true},
+		{size: "1..5G", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := ParseSize(tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.size, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseSize(%q) = %d, want %d", tt.size, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+
+	TolerationOperatorEqual  = "Equal"
+	TolerationOperatorExists = "Exists"
+)
+
+func ParseLabels(labels string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, label := range strings.Split(labels, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label, expected key=value: %s", label)
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+func FormatLabels(labels map[string]string) []string {
+	formatted := make([]string, 0, len(labels))
+	for key, value := range labels {
+		formatted = append(formatted, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
+func validateEffect(effect string) error {
+	switch effect {
+	case TaintEffectNoSchedule, TaintEffectPreferNoSchedule:
+		return nil
+	default:
+		return fmt.Errorf("unknown taint effect: %s", effect)
+	}
+}
+
+// ParseTaint parses taints in key=value:Effect or key:Effect form.
+func ParseTaint(taint string) (*Taint, error) {
+	keyValue, effect, ok := strings.Cut(taint, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid taint, expected key=value:Effect: %s", taint)
+	}
+	if err := validateEffect(effect); err != nil {
+		return nil, err
+	}
+	key, value, _ := strings.Cut(keyValue, "=")
+	t := &Taint{Key: key, Value: value, Effect: effect}
+	if err := ValidateTaint(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ValidateTaint checks a taint survives Format and ParseTaint, as node specs
+// are kept formatted.
+func ValidateTaint(taint *Taint) error {
+	if taint.Key == "" {
+		return fmt.Errorf("invalid taint, key is empty")
+	}
+	if strings.ContainsAny(taint.Key, ":=,") {
+		return fmt.Errorf("invalid taint key %q: must not contain ':', '=' or ','", taint.Key)
+	}
+	if strings.ContainsAny(taint.Value, ":,") {
+		return fmt.Errorf("invalid taint value %q: must not contain ':' or ','", taint.Value)
+	}
+	return validateEffect(taint.Effect)
+}
+
+func (t *Taint) Format() string {
+	if t.Value == "" {
+		return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+	}
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+}
+
+// ParseTolerations parses comma separated tolerations, each in key=value:Effect,
+// key:Effect or key form. Omitting the value tolerates any value of the key and
+// omitting the effect tolerates every effect.
+func ParseTolerations(tolerations string) ([]*Toleration, error) {
+	parsed := make([]*Toleration, 0)
+	for _, toleration := range strings.Split(tolerations, ",") {
+		toleration = strings.TrimSpace(toleration)
+		if toleration == "" {
+			continue
+		}
+		keyValue, effect, _ := strings.Cut(toleration, ":")
+		if effect != "" {
+			if err := validateEffect(effect); err != nil {
+				return nil, err
+			}
+		}
+		key, value, hasValue := strings.Cut(keyValue, "=")
+		operator := TolerationOperatorExists
+		if hasValue {
+			operator = TolerationOperatorEqual
+		}
+		parsed = append(parsed, &Toleration{Key: key, Operator: operator, Value: value, Effect: effect})
+	}
+	return parsed, nil
+}
+
+func (t *Toleration) Tolerates(taint *Taint) bool {
+	if t.Effect != "" && t.Effect != taint.Effect {
+		return false
+	}
+	if t.Key == "" {
+		return t.Operator == TolerationOperatorExists
+	}
+	if t.Key != taint.Key {
+		return false
+	}
+	return t.Operator == TolerationOperatorExists || t.Value == taint.Value
+}
+
+func IsTolerated(taint *Taint, tolerations []*Toleration) bool {
+	for _, toleration := range tolerations {
+		if toleration.Tolerates(taint) {
+			return true
+		}
+	}
+	return false
+}
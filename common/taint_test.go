@@ -0,0 +1,96 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTaint(t *testing.T) {
+	tests := []struct {
+		want    *Taint
+		taint   string
+		wantErr bool
+	}{
+		{taint: "gpu=true:NoSchedule", want: &Taint{Key: "gpu", Value: "true", Effect: TaintEffectNoSchedule}},
+		{taint: "spot:PreferNoSchedule", want: &Taint{Key: "spot", Effect: TaintEffectPreferNoSchedule}},
+		{taint: "gpu=true", wantErr: true},
+		{taint: "gpu=true:NoExecute", wantErr: true},
+		{taint: "=true:NoSchedule", wantErr: true},
+		{taint: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.taint, func(t *testing.T) {
+			got, err := ParseTaint(tt.taint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTaint(%q) error = %v, wantErr %v", tt.taint, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseTaint(%q) = %v, want %v", tt.taint, got, tt.want)
+			}
+			if got != nil && got.Format() != tt.taint {
+				t.Fatalf("Format() = %q, want %q", got.Format(), tt.taint)
+			}
+		})
+	}
+}
+
+func TestParseTolerations(t *testing.T) {
+	tests := []struct {
+		tolerations string
+		want        []*Toleration
+		wantErr     bool
+	}{
+		{tolerations: "", want: []*Toleration{}},
+		{
+			tolerations: "gpu=true:NoSchedule",
+			want:        []*Toleration{{Key: "gpu", Operator: TolerationOperatorEqual, Value: "true", Effect: TaintEffectNoSchedule}},
+		},
+		{
+			tolerations: "gpu, spot:PreferNoSchedule",
+			want: []*Toleration{
+				{Key: "gpu", Operator: TolerationOperatorExists},
+				{Key: "spot", Operator: TolerationOperatorExists, Effect: TaintEffectPreferNoSchedule},
+			},
+		},
+		{tolerations: "gpu:NoExecute", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tolerations, func(t *testing.T) {
+			got, err := ParseTolerations(tt.tolerations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTolerations(%q) error = %v, wantErr %v", tt.tolerations, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseTolerations(%q) = %v, want %v", tt.tolerations, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTolerated(t *testing.T) {
+	taint := &Taint{Key: "gpu", Value: "true", Effect: TaintEffectNoSchedule}
+	tests := []struct {
+		name        string
+		tolerations []*Toleration
+		want        bool
+	}{
+		{name: "none", want: false},
+		{name: "equal", tolerations: []*Toleration{{Key: "gpu", Operator: TolerationOperatorEqual, Value: "true"}}, want: true},
+		{name: "other value", tolerations: []*Toleration{{Key: "gpu", Operator: TolerationOperatorEqual, Value: "no"}}, want: false},
+		{name: "exists", tolerations: []*Toleration{{Key: "gpu", Operator: TolerationOperatorExists}}, want: true},
+		{name: "other key", tolerations: []*Toleration{{Key: "spot", Operator: TolerationOperatorExists}}, want: false},
+		{
+			name:        "other effect",
+			tolerations: []*Toleration{{Key: "gpu", Operator: TolerationOperatorExists, Effect: TaintEffectPreferNoSchedule}},
+			want:        false,
+		},
+		{name: "wildcard", tolerations: []*Toleration{{Operator: TolerationOperatorExists}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTolerated(taint, tt.tolerations); got != tt.want {
+				t.Fatalf("IsTolerated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
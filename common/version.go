@@ -0,0 +1,87 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Version is the binary version, set on release builds with
+// -ldflags "-X github.com/erayarslan/multiverse/common.Version=v1.2.3".
+var Version = "dev"
+
+const (
+	// ProtocolVersion is bumped on every change to the cluster or api
+	// protocol, MinProtocolVersion only when older peers can not be served.
+	ProtocolVersion    = 2
+	MinProtocolVersion = 1
+
+	CapabilityTunnel    = "tunnel"
+	CapabilityDelta     = "delta"
+	CapabilityLeave     = "leave"
+	CapabilityExec      = "exec"
+	CapabilityCloudInit = "cloud-init"
+)
+
+// Capabilities are the optional protocol features this binary speaks, peers
+// only use the ones both sides advertise.
+var Capabilities = []string{CapabilityTunnel, CapabilityDelta, CapabilityLeave, CapabilityExec, CapabilityCloudInit}
+
+const (
+	versionKey      = "version"
+	protocolKey     = "protocol"
+	capabilitiesKey = "capabilities"
+)
+
+// PeerVersion is what a peer advertised about itself at connect.
+type PeerVersion struct {
+	Version      string
+	Capabilities []string
+	Protocol     int
+}
+
+// VersionMetadata advertises this binary to peers.
+func VersionMetadata() metadata.MD {
+	md := metadata.Pairs(
+		versionKey, Version,
+		protocolKey, strconv.Itoa(ProtocolVersion),
+	)
+	md.Append(capabilitiesKey, Capabilities...)
+	return md
+}
+
+// PeerVersionFromMetadata reads what a peer advertised, peers built before
+// versioning advertise nothing and come out as protocol 0.
+func PeerVersionFromMetadata(md metadata.MD) PeerVersion {
+	v := PeerVersion{
+		Version:      "unknown",
+		Capabilities: md.Get(capabilitiesKey),
+	}
+	if values := md.Get(versionKey); len(values) > 0 {
+		v.Version = values[0]
+	}
+	if values := md.Get(protocolKey); len(values) > 0 {
+		v.Protocol, _ = strconv.Atoi(values[0])
+	}
+	return v
+}
+
+// Has reports whether the peer advertised capability.
+func (v PeerVersion) Has(capability string) bool {
+	return slices.Contains(v.Capabilities, capability)
+}
+
+// Compatible returns an error when the peer speaks a protocol older than
+// this binary still serves.
+func (v PeerVersion) Compatible() error {
+	if v.Protocol < MinProtocolVersion {
+		return fmt.Errorf("%s is older than protocol %d, upgrade it", v, MinProtocolVersion)
+	}
+	return nil
+}
+
+func (v PeerVersion) String() string {
+	return fmt.Sprintf("%s (protocol %d)", v.Version, v.Protocol)
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ClientContext struct {
+	Name          string `yaml:"name"`
+	APIServerAddr string `yaml:"api-server-addr"`
+	Token         string `yaml:"token,omitempty"`
+	CAFile        string `yaml:"ca-file,omitempty"`
+	CertFile      string `yaml:"cert-file,omitempty"`
+	KeyFile       string `yaml:"key-file,omitempty"`
+}
+
+type ClientConfig struct {
+	CurrentContext string           `yaml:"current-context"`
+	Contexts       []*ClientContext `yaml:"contexts"`
+}
+
+func DefaultClientConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "config.yaml"
+	}
+	return filepath.Join(dir, "multiverse", "config.yaml")
+}
+
+func LoadClientConfig(path string) (*ClientConfig, error) {
+	cfg := &ClientConfig{Contexts: make([]*ClientContext, 0)}
+
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(bytes, cfg); err != nil {
+		return nil, fmt.Errorf("error while parsing client config %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool, len(cfg.Contexts))
+	for _, c := range cfg.Contexts {
+		if c.Name == "" {
+			return nil, fmt.Errorf("client config %s has a context without name", path)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("client config %s has duplicate context: %s", path, c.Name)
+		}
+		seen[c.Name] = true
+	}
+
+	return cfg, nil
+}
+
+func (c *ClientConfig) Save(path string) error {
+	bytes, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// contexts may hold tokens, so the file is only readable by the user
+	return os.WriteFile(path, bytes, 0o600)
+}
+
+func (c *ClientConfig) Context(name string) (*ClientContext, bool) {
+	for _, context := range c.Contexts {
+		if context.Name == name {
+			return context, true
+		}
+	}
+	return nil, false
+}
+
+// Current returns the context selected by name, or the current context when
+// name is empty. It returns nil when no context is selected.
+func (c *ClientConfig) Current(name string) (*ClientContext, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	context, ok := c.Context(name)
+	if !ok {
+		return nil, fmt.Errorf("context not found: %s", name)
+	}
+	return context, nil
+}
+
+func (c *ClientConfig) SetContext(context *ClientContext) {
+	for i, existing := range c.Contexts {
+		if existing.Name == context.Name {
+			c.Contexts[i] = context
+			return
+		}
+	}
+	c.Contexts = append(c.Contexts, context)
+}
+
+func (c *ClientConfig) DeleteContext(name string) bool {
+	for i, existing := range c.Contexts {
+		if existing.Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.CurrentContext == name {
+				c.CurrentContext = ""
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -2,24 +2,76 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
+	"time"
 )
 
 type Config struct {
-	LaunchInstanceName    string
-	MultipassKeyFilePath  string
+	flags                 *flag.FlagSet
+	TaintSpec             string
+	WatchTypes            string
+	ReservedDisk          string
 	MultipassAddr         string
 	MultipassProxyBind    string
 	MultipassCertFilePath string
+	MultipassPassphrase   string
 	MasterAddr            string
 	ShellInstanceName     string
 	APIServerAddr         string
 	NodeName              string
+	DataDir               string
+	MetricsAddr           string
+	WebhookConfigFilePath string
+	Labels                string
 	LaunchDiskSpace       string
 	LaunchMemSize         string
 	LaunchNumCores        string
+	LaunchNodeSelector    string
+	LaunchAffinity        string
+	DrainAction           string
+	LaunchTolerations     string
+	TargetNodeName        string
+	MultipassKeyFilePath  string
+	LaunchInstanceName    string
+	LaunchAntiAffinity    string
+	ReservedMemory        string
+	TopSort               string
+	GatewayAddr           string
+	GatewayOrigins        string
+	APITokenFilePath      string
+	APITLSCertFilePath    string
+	APITLSKeyFilePath     string
+	APITLSClientCAPath    string
+	RaftAddr              string
+	RaftPeers             string
+	DNSAddr               string
+	DNSDomain             string
+	Export                string
+	SSHAddr               string
+	SSHHostKeyFilePath    string
+	SSHAuthorizedKeysPath string
+	ExportFormat          string
+	ConfigFilePath        string
+	Context               string
+	ClientConfigFilePath  string
+	DrainTimeout          time.Duration
+	ShutdownTimeout       time.Duration
+	ReconnectMaxBackoff   time.Duration
+	StateInterval         time.Duration
+	ReconnectMaxAttempts  int
+	CPUOvercommitRatio    float64
+	MemoryOvercommitRatio float64
+	DiskOvercommitRatio   float64
+	ReservedCPU           int
+	TopInterval           time.Duration
+	DNSTTL                time.Duration
+	HistoryInterval       time.Duration
+	HistorySize           int
 	IsMaster              bool
 	IsWorker              bool
 	Shell                 bool
@@ -27,20 +79,31 @@ type Config struct {
 	Nodes                 bool
 	Launch                bool
 	Info                  bool
+	Taint                 bool
+	Untaint               bool
+	Cordon                bool
+	Uncordon              bool
+	Drain                 bool
+	DrainForce            bool
 	IsClient              bool
+	WebhookDeliveries     bool
+	Watch                 bool
+	Top                   bool
+	HistoryPersist        bool
+	PrintConfig           bool
 }
 
-func NewConfig() *Config {
-	cfg := &Config{}
-
+func (cfg *Config) bind(fs *flag.FlagSet) error {
 	dir, err := os.UserConfigDir()
 	if err != nil {
-		log.Fatalf("error while getting user config dir: %v", err)
+		return fmt.Errorf("error while getting user config dir: %w", err)
 	}
 
+	defaultDataDir := filepath.Join(dir, "multiverse")
+
 	hostname, err := os.Hostname()
 	if err != nil {
-		log.Fatalf("error while getting hostname: %v", err)
+		return fmt.Errorf("error while getting hostname: %w", err)
 	}
 
 	cfg.NodeName = hostname
@@ -58,27 +121,166 @@ func NewConfig() *Config {
 	defaultMultipassCertFilePath := dir + "/multipass-client-certificate/multipass_cert.pem"
 	defaultMultiPassKeyFilePath := dir + "/multipass-client-certificate/multipass_cert_key.pem"
 
-	flag.BoolVar(&cfg.IsMaster, "master", false, "run as master")
-	flag.BoolVar(&cfg.IsWorker, "worker", false, "run as worker")
-	flag.BoolVar(&cfg.IsClient, "client", false, "run as client")
-	flag.StringVar(&cfg.MasterAddr, "master-addr", "localhost:1337", "master addr to listen on")
-	flag.StringVar(&cfg.APIServerAddr, "api-server-addr", "localhost:1338", "api server addr to listen on")
-	flag.StringVar(&cfg.MultipassAddr, "multipass-addr", defaultMultipassAddr, "multipass addr to connect")
-	flag.StringVar(&cfg.MultipassProxyBind, "multipass-proxy-bind", "localhost", "multipass proxy bind to listen on")
-	flag.StringVar(&cfg.MultipassCertFilePath, "multipass-cert-file", defaultMultipassCertFilePath, "multipass cert file for tls")
-	flag.StringVar(&cfg.MultipassKeyFilePath, "multipass-key-file", defaultMultiPassKeyFilePath, "multipass key file for tls")
-	flag.BoolVar(&cfg.Instances, "instances", false, "list instances")
-	flag.BoolVar(&cfg.Nodes, "nodes", false, "list nodes")
-	flag.BoolVar(&cfg.Shell, "shell", false, "run as shell")
-	flag.BoolVar(&cfg.Launch, "launch", false, "launch instance")
-	flag.BoolVar(&cfg.Info, "info", false, "get info")
-	flag.StringVar(&cfg.ShellInstanceName, "shell-instance-name", "primary", "shell instance name")
-	flag.StringVar(&cfg.LaunchInstanceName, "launch-instance-name", "primary", "launch instance name")
-	flag.StringVar(&cfg.LaunchNumCores, "launch-num-cores", "1", "launch instance num cores")
-	flag.StringVar(&cfg.LaunchMemSize, "launch-mem-size", "1G", "launch instance mem size")
-	flag.StringVar(&cfg.LaunchDiskSpace, "launch-disk-space", "4G", "launch instance disk space")
+	fs.StringVar(&cfg.ConfigFilePath, "config", "", "yaml or toml config file keyed by flag name")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print effective config and exit")
+	fs.BoolVar(&cfg.IsMaster, "master", false, "run as master")
+	fs.BoolVar(&cfg.IsWorker, "worker", false, "run as worker")
+	fs.BoolVar(&cfg.IsClient, "client", false, "run as client")
+	fs.StringVar(&cfg.MasterAddr, "master-addr", "localhost:1337",
+		"master addr to listen on, workers take comma separated masters to fail over between")
+	fs.StringVar(&cfg.APIServerAddr, "api-server-addr", "localhost:1338",
+		"api server addr to listen on, clients take comma separated masters to fail over between")
+	fs.StringVar(&cfg.Context, "context", "", "client context to use instead of the current context")
+	fs.StringVar(&cfg.ClientConfigFilePath, "client-config", DefaultClientConfigPath(), "client config file holding contexts")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 10*time.Second, "master and worker graceful shutdown timeout")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "prometheus metrics addr to listen on for master and worker")
+	fs.StringVar(&cfg.GatewayAddr, "gateway-addr", "", "rest gateway addr to listen on for master")
+	fs.StringVar(&cfg.GatewayOrigins, "gateway-allowed-origins", "",
+		"origins besides the gateway itself allowed to open shells as https://host,https://host")
+	fs.StringVar(&cfg.APITokenFilePath, "api-token-file", "", "master file holding the bearer token api callers must send")
+	fs.StringVar(&cfg.APITLSCertFilePath, "api-tls-cert-file", "", "master api server tls cert file, empty serves plain text")
+	fs.StringVar(&cfg.APITLSKeyFilePath, "api-tls-key-file", "", "master api server tls key file")
+	fs.StringVar(&cfg.APITLSClientCAPath, "api-tls-client-ca-file", "",
+		"master ca file to verify api client certificates with, empty accepts clients without one")
+	fs.StringVar(&cfg.RaftAddr, "raft-addr", "", "master raft addr to listen on for replication, empty runs a single master")
+	fs.StringVar(&cfg.RaftPeers, "raft-peers", "", "raft addrs of all masters, this one included, to bootstrap the cluster with as addr,addr")
+	fs.StringVar(&cfg.DNSAddr, "dns-addr", "", "master dns addr to listen on for udp and tcp, empty disables dns")
+	fs.StringVar(&cfg.DNSDomain, "dns-domain", "multiverse", "dns domain to answer instance names under")
+	fs.DurationVar(&cfg.DNSTTL, "dns-ttl", 5*time.Second, "dns record ttl")
+	fs.StringVar(&cfg.SSHAddr, "ssh-addr", "", "master ssh addr to listen on, empty disables the ssh gateway")
+	fs.StringVar(&cfg.SSHHostKeyFilePath, "ssh-host-key-file", "",
+		"ssh host key file, generated as ssh_host_ed25519_key under data-dir when empty")
+	fs.StringVar(&cfg.SSHAuthorizedKeysPath, "ssh-authorized-keys-file", "",
+		"authorized_keys file of identities allowed through the ssh gateway next to registered keys, named by key comments")
+	fs.StringVar(&cfg.DataDir, "data-dir", defaultDataDir, "master data dir to persist cluster state")
+	fs.StringVar(&cfg.WebhookConfigFilePath, "webhook-config-file", "", "master webhook sinks json file")
+	fs.DurationVar(&cfg.ReconnectMaxBackoff, "reconnect-max-backoff", 30*time.Second, "worker max backoff between reconnects to master")
+	fs.IntVar(&cfg.ReconnectMaxAttempts, "reconnect-max-attempts", 0, "worker reconnect attempts before giving up, 0 never gives up")
+	fs.DurationVar(&cfg.StateInterval, "state-interval", 10*time.Second, "worker multipass poll interval for state sync, at most 15s")
+	fs.StringVar(&cfg.Labels, "labels", "", "node labels to advertise as key=value,key=value")
+	fs.StringVar(&cfg.MultipassAddr, "multipass-addr", defaultMultipassAddr, "multipass addr to connect")
+	fs.StringVar(&cfg.MultipassProxyBind, "multipass-proxy-bind", "localhost", "multipass proxy bind to listen on")
+	fs.StringVar(&cfg.MultipassCertFilePath, "multipass-cert-file", defaultMultipassCertFilePath, "multipass cert file for tls")
+	fs.StringVar(&cfg.MultipassKeyFilePath, "multipass-key-file", defaultMultiPassKeyFilePath, "multipass key file for tls")
+	fs.StringVar(&cfg.MultipassPassphrase, "multipass-passphrase", "", "multipass passphrase to trust client certificate")
+	fs.BoolVar(&cfg.Instances, "instances", false, "list instances")
+	fs.BoolVar(&cfg.Nodes, "nodes", false, "list nodes")
+	fs.BoolVar(&cfg.Shell, "shell", false, "run as shell")
+	fs.BoolVar(&cfg.Launch, "launch", false, "launch instance")
+	fs.BoolVar(&cfg.Info, "info", false, "get info")
+	fs.StringVar(&cfg.ShellInstanceName, "shell-instance-name", "primary", "shell instance name")
+	fs.StringVar(&cfg.LaunchInstanceName, "launch-instance-name", "primary", "launch instance name")
+	fs.StringVar(&cfg.LaunchNumCores, "launch-num-cores", "1", "launch instance num cores")
+	fs.StringVar(&cfg.LaunchMemSize, "launch-mem-size", "1G", "launch instance mem size")
+	fs.StringVar(&cfg.LaunchDiskSpace, "launch-disk-space", "4G", "launch instance disk space")
+	fs.StringVar(&cfg.LaunchNodeSelector, "launch-node-selector", "", "launch instance on nodes labeled key=value,key=value")
+	fs.StringVar(&cfg.LaunchAffinity, "launch-affinity", "", "launch instance next to instances name,name")
+	fs.StringVar(&cfg.LaunchAntiAffinity, "launch-anti-affinity", "", "launch instance away from instances name,name")
+	fs.StringVar(&cfg.LaunchTolerations, "launch-tolerations", "", "launch instance tolerating taints key=value:Effect,key")
+	fs.BoolVar(&cfg.Taint, "taint", false, "add taint to node")
+	fs.BoolVar(&cfg.Untaint, "untaint", false, "remove taint from node")
+	fs.StringVar(&cfg.TargetNodeName, "target-node-name", "", "node name to taint, cordon or drain")
+	fs.StringVar(&cfg.TargetNodeName, "taint-node-name", "", "alias of -target-node-name")
+	fs.BoolVar(&cfg.Cordon, "cordon", false, "exclude node from placement")
+	fs.BoolVar(&cfg.Uncordon, "uncordon", false, "include node in placement")
+	fs.BoolVar(&cfg.Drain, "drain", false, "cordon node and stop or suspend its instances")
+	fs.StringVar(&cfg.DrainAction, "drain-action", "stop", "drain action, stop or suspend")
+	fs.DurationVar(&cfg.DrainTimeout, "drain-timeout", 2*time.Minute, "drain timeout per instance")
+	fs.BoolVar(&cfg.DrainForce, "drain-force", false, "force stop instances which fail to stop in time")
+	fs.BoolVar(&cfg.WebhookDeliveries, "webhook-deliveries", false, "list recent webhook deliveries")
+	fs.BoolVar(&cfg.Watch, "watch", false, "watch cluster events")
+	fs.StringVar(&cfg.WatchTypes, "watch-types", "", "event types to watch as NODE_JOINED,INSTANCE_STATE_CHANGED")
+	fs.BoolVar(&cfg.Top, "top", false, "show live node and instance usage")
+	fs.StringVar(&cfg.TopSort, "top-sort", "cpu", "top sort order, cpu or memory")
+	fs.DurationVar(&cfg.TopInterval, "top-interval", 2*time.Second, "top refresh interval")
+	fs.StringVar(&cfg.Export, "export", "", "export instances as ssh-config or ansible-inventory")
+	fs.StringVar(&cfg.ExportFormat, "export-format", "ini", "ansible inventory format, ini or json")
+	fs.DurationVar(&cfg.HistoryInterval, "history-interval", 15*time.Second, "master metrics history sample interval")
+	fs.IntVar(&cfg.HistorySize, "history-size", 720, "master metrics history samples to keep per node and instance")
+	fs.BoolVar(&cfg.HistoryPersist, "history-persist", false, "persist master metrics history to data dir")
+	fs.StringVar(&cfg.TaintSpec, "taint-spec", "", "taint as key=value:Effect, or key to remove")
+	fs.Float64Var(&cfg.CPUOvercommitRatio, "cpu-overcommit-ratio", 1, "allocatable cpu overcommit ratio")
+	fs.Float64Var(&cfg.MemoryOvercommitRatio, "memory-overcommit-ratio", 1, "allocatable memory overcommit ratio")
+	fs.Float64Var(&cfg.DiskOvercommitRatio, "disk-overcommit-ratio", 1, "allocatable disk overcommit ratio")
+	fs.IntVar(&cfg.ReservedCPU, "reserved-cpu", 0, "cpu cores reserved for host")
+	fs.StringVar(&cfg.ReservedMemory, "reserved-memory", "0", "memory reserved for host")
+	fs.StringVar(&cfg.ReservedDisk, "reserved-disk", "0", "disk space reserved for host")
+
+	return nil
+}
+
+func NewConfig() *Config {
+	cfg := &Config{flags: flag.CommandLine}
+
+	if err := cfg.bind(flag.CommandLine); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if err := ApplyEnv(flag.CommandLine); err != nil {
+		log.Fatalf("error while reading environment: %v", err)
+	}
 
 	flag.Parse()
 
+	if cfg.ConfigFilePath != "" {
+		if err := ApplyFile(flag.CommandLine, cfg.ConfigFilePath); err != nil {
+			log.Fatalf("error while reading config file: %v", err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid config:\n%v", err)
+	}
+
+	if cfg.PrintConfig {
+		if err := Print(os.Stdout, flag.CommandLine); err != nil {
+			log.Fatalf("error while printing config: %v", err)
+		}
+		os.Exit(0)
+	}
+
 	return cfg
 }
+
+// Reload reads the command line, environment and config file again into a
+// new config, leaving the receiver untouched.
+func (cfg *Config) Reload() (*Config, error) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	next := &Config{flags: fs}
+
+	if err := next.bind(fs); err != nil {
+		return nil, err
+	}
+
+	if err := ApplyEnv(fs); err != nil {
+		return nil, fmt.Errorf("error while reading environment: %w", err)
+	}
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return nil, err
+	}
+
+	if next.ConfigFilePath != "" {
+		if err := ApplyFile(fs, next.ConfigFilePath); err != nil {
+			return nil, fmt.Errorf("error while reading config file: %w", err)
+		}
+	}
+
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	return next, nil
+}
+
+// Changed returns the names of the flags whose values differ in next.
+func (cfg *Config) Changed(next *Config) []string {
+	changed := make([]string, 0)
+	cfg.flags.VisitAll(func(f *flag.Flag) {
+		if n := next.flags.Lookup(f.Name); n != nil && n.Value.String() != f.Value.String() {
+			changed = append(changed, f.Name)
+		}
+	})
+	return changed
+}
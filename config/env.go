@@ -0,0 +1,62 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const envPrefix = "MULTIVERSE_"
+
+// EnvName maps a flag name to its environment variable, e.g. api-server-addr
+// to MULTIVERSE_API_SERVER_ADDR.
+func EnvName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// ApplyEnv sets the named flags of fs, or every flag when none are named,
+// from their environment variables. It runs before parsing so command line
+// flags still take precedence.
+func ApplyEnv(fs *flag.FlagSet, names ...string) error {
+	apply := func(f *flag.Flag) error {
+		value, ok := os.LookupEnv(EnvName(f.Name))
+		if !ok {
+			return nil
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvName(f.Name), err)
+		}
+		return nil
+	}
+
+	if len(names) > 0 {
+		for _, name := range names {
+			if f := fs.Lookup(name); f != nil {
+				if err := apply(f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err == nil {
+			err = apply(f)
+		}
+	})
+	return err
+}
+
+// IsSet reports whether the named flag was given on the command line or environment.
+func IsSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
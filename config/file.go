@@ -0,0 +1,145 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileOnlyFlags can not be set from a config file.
+var fileOnlyFlags = map[string]bool{"config": true, "print-config": true}
+
+var redactedFlags = map[string]bool{"multipass-passphrase": true}
+
+func decodeFile(path string) (map[string]any, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(bytes, &values)
+	case ".yaml", ".yml", "":
+		err = yaml.Unmarshal(bytes, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// fileValue flattens a config file value into its flag form, lists join with
+// commas and maps become sorted key=value pairs.
+func fileValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+"="+s)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	case time.Duration:
+		return v.String(), nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// ApplyFile sets every flag of fs not already given on the command line or
+// environment from the yaml or toml file at path, keyed by flag name.
+func ApplyFile(fs *flag.FlagSet, path string) error {
+	values, err := decodeFile(path)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if fs.Lookup(key) == nil || fileOnlyFlags[key] {
+			return fmt.Errorf("unknown key %q in config file %s", key, path)
+		}
+		if set[key] {
+			continue
+		}
+		value, err := fileValue(values[key])
+		if err != nil {
+			return fmt.Errorf("invalid %s in config file %s: %w", key, path, err)
+		}
+		if err = fs.Set(key, value); err != nil {
+			return fmt.Errorf("invalid %s in config file %s: %w", key, path, err)
+		}
+	}
+
+	return nil
+}
+
+// Print writes the effective value of every flag of fs as yaml.
+func Print(w io.Writer, fs *flag.FlagSet) error {
+	values := make(map[string]any)
+	fs.VisitAll(func(f *flag.Flag) {
+		if fileOnlyFlags[f.Name] {
+			return
+		}
+		var value any = f.Value.String()
+		if getter, ok := f.Value.(flag.Getter); ok {
+			value = getter.Get()
+		}
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		if redactedFlags[f.Name] && f.Value.String() != "" {
+			value = "<redacted>"
+		}
+		values[f.Name] = value
+	})
+
+	bytes, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/erayarslan/multiverse/common"
+
+	goDns "github.com/miekg/dns"
+)
+
+func validateAddr(name string, addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("invalid %s %q: %w", name, addr, err)
+	}
+	return nil
+}
+
+func validateAddrs(name string, addrs string) error {
+	errs := make([]error, 0)
+	for _, addr := range common.ParseAddrs(addrs) {
+		errs = append(errs, validateAddr(name, addr))
+	}
+	return errors.Join(errs...)
+}
+
+func validateReadable(name string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return file.Close()
+}
+
+func validateAPIAuth(cfg *Config) error {
+	errs := make([]error, 0)
+	if (cfg.APITLSCertFilePath == "") != (cfg.APITLSKeyFilePath == "") {
+		errs = append(errs, fmt.Errorf("api-tls-cert-file and api-tls-key-file must be set together"))
+	}
+	if cfg.APITLSClientCAPath != "" && cfg.APITLSCertFilePath == "" {
+		errs = append(errs, fmt.Errorf("api-tls-client-ca-file needs api-tls-cert-file"))
+	}
+	// the gateway forwards tokens, so it only serves them over tls
+	if cfg.APITokenFilePath != "" && cfg.GatewayAddr != "" && cfg.APITLSCertFilePath == "" {
+		errs = append(errs, fmt.Errorf("gateway-addr with api-token-file needs api-tls-cert-file"))
+	}
+	// the gateway shows the master certificate, so its callers need a token
+	if cfg.APITLSClientCAPath != "" && cfg.GatewayAddr != "" && cfg.APITokenFilePath == "" {
+		errs = append(errs, fmt.Errorf("gateway-addr with api-tls-client-ca-file needs api-token-file"))
+	}
+	files := []struct {
+		name string
+		path string
+	}{
+		{"api-token-file", cfg.APITokenFilePath},
+		{"api-tls-cert-file", cfg.APITLSCertFilePath},
+		{"api-tls-key-file", cfg.APITLSKeyFilePath},
+		{"api-tls-client-ca-file", cfg.APITLSClientCAPath},
+	}
+	for _, file := range files {
+		if file.path != "" {
+			errs = append(errs, validateReadable(file.name, file.path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateCertificate allows the multipass cert and key to be missing together,
+// since the worker generates them, but both must be readable once present.
+func validateCertificate(certFilePath string, keyFilePath string) error {
+	_, certErr := os.Stat(certFilePath)
+	_, keyErr := os.Stat(keyFilePath)
+	certMissing, keyMissing := errors.Is(certErr, os.ErrNotExist), errors.Is(keyErr, os.ErrNotExist)
+
+	if certMissing && keyMissing {
+		return nil
+	}
+	if certMissing != keyMissing {
+		return fmt.Errorf("multipass-cert-file and multipass-key-file must both exist or both be missing")
+	}
+	return errors.Join(
+		validateReadable("multipass-cert-file", certFilePath),
+		validateReadable("multipass-key-file", keyFilePath),
+	)
+}
+
+// Validate reports every invalid setting at once.
+func (cfg *Config) Validate() error {
+	errs := make([]error, 0)
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	check(validateAddrs("master-addr", cfg.MasterAddr))
+	check(validateAddrs("api-server-addr", cfg.APIServerAddr))
+	check(validateAddr("metrics-addr", cfg.MetricsAddr))
+	check(validateAddr("gateway-addr", cfg.GatewayAddr))
+	check(validateAddr("raft-addr", cfg.RaftAddr))
+	check(validateAddr("dns-addr", cfg.DNSAddr))
+	check(validateAddr("ssh-addr", cfg.SSHAddr))
+	check(validateAddrs("raft-peers", cfg.RaftPeers))
+
+	if numCores, err := strconv.ParseInt(cfg.LaunchNumCores, 10, 32); err != nil || numCores <= 0 {
+		check(fmt.Errorf("invalid launch-num-cores %q: must be a positive integer", cfg.LaunchNumCores))
+	}
+
+	sizes := []struct {
+		name  string
+		value string
+	}{
+		{"launch-mem-size", cfg.LaunchMemSize},
+		{"launch-disk-space", cfg.LaunchDiskSpace},
+		{"reserved-memory", cfg.ReservedMemory},
+		{"reserved-disk", cfg.ReservedDisk},
+	}
+	for _, size := range sizes {
+		if _, err := common.ParseSize(size.value); err != nil {
+			check(fmt.Errorf("invalid %s %q: %w", size.name, size.value, err))
+		}
+	}
+
+	if _, err := common.ParseLabels(cfg.Labels); err != nil {
+		check(fmt.Errorf("invalid labels: %w", err))
+	}
+	if _, err := common.ParseLabels(cfg.LaunchNodeSelector); err != nil {
+		check(fmt.Errorf("invalid launch-node-selector: %w", err))
+	}
+	if _, err := common.ParseTolerations(cfg.LaunchTolerations); err != nil {
+		check(fmt.Errorf("invalid launch-tolerations: %w", err))
+	}
+
+	if cfg.CPUOvercommitRatio <= 0 || cfg.MemoryOvercommitRatio <= 0 || cfg.DiskOvercommitRatio <= 0 {
+		check(fmt.Errorf("overcommit ratios must be greater than zero"))
+	}
+	if cfg.ReservedCPU < 0 {
+		check(fmt.Errorf("invalid reserved-cpu %d: must not be negative", cfg.ReservedCPU))
+	}
+
+	if cfg.DrainAction != "stop" && cfg.DrainAction != "suspend" {
+		check(fmt.Errorf("invalid drain-action %q: must be stop or suspend", cfg.DrainAction))
+	}
+	if cfg.TopSort != "cpu" && cfg.TopSort != "memory" {
+		check(fmt.Errorf("invalid top-sort %q: must be cpu or memory", cfg.TopSort))
+	}
+	if cfg.Export != "" && cfg.Export != "ssh-config" && cfg.Export != "ansible-inventory" {
+		check(fmt.Errorf("invalid export %q: must be ssh-config or ansible-inventory", cfg.Export))
+	}
+	if cfg.ExportFormat != "ini" && cfg.ExportFormat != "json" {
+		check(fmt.Errorf("invalid export-format %q: must be ini or json", cfg.ExportFormat))
+	}
+	if cfg.DrainTimeout <= 0 || cfg.TopInterval <= 0 || cfg.HistoryInterval <= 0 || cfg.ShutdownTimeout <= 0 {
+		check(fmt.Errorf("drain-timeout, top-interval, history-interval and shutdown-timeout must be greater than zero"))
+	}
+	if _, ok := goDns.IsDomainName(cfg.DNSDomain); !ok || cfg.DNSDomain == "" || cfg.DNSDomain == "." {
+		check(fmt.Errorf("invalid dns-domain %q", cfg.DNSDomain))
+	}
+	if cfg.DNSTTL < time.Second {
+		check(fmt.Errorf("invalid dns-ttl %v: must be at least a second", cfg.DNSTTL))
+	}
+	// a single late sync must not mark the node not ready
+	if cfg.StateInterval <= 0 || cfg.StateInterval > common.NotReadyTimeout/2 {
+		check(fmt.Errorf("invalid state-interval %v: must be greater than zero and at most %v, half the not ready timeout",
+			cfg.StateInterval, common.NotReadyTimeout/2))
+	}
+	if cfg.ReconnectMaxBackoff <= 0 {
+		check(fmt.Errorf("invalid reconnect-max-backoff %v: must be greater than zero", cfg.ReconnectMaxBackoff))
+	}
+	if cfg.ReconnectMaxAttempts < 0 {
+		check(fmt.Errorf("invalid reconnect-max-attempts %d: must not be negative", cfg.ReconnectMaxAttempts))
+	}
+	if cfg.HistorySize <= 0 {
+		check(fmt.Errorf("invalid history-size %d: must be greater than zero", cfg.HistorySize))
+	}
+
+	if cfg.IsMaster && (len(common.ParseAddrs(cfg.MasterAddr)) == 0 || len(common.ParseAddrs(cfg.APIServerAddr)) == 0) {
+		check(fmt.Errorf("master needs a master-addr and an api-server-addr to listen on"))
+	}
+	if cfg.IsWorker && len(common.ParseAddrs(cfg.MasterAddr)) == 0 {
+		check(fmt.Errorf("worker needs a master-addr to connect"))
+	}
+	if cfg.IsMaster && cfg.RaftAddr != "" {
+		if host, _, err := net.SplitHostPort(cfg.RaftAddr); err == nil && (host == "" || net.ParseIP(host).IsUnspecified()) {
+			check(fmt.Errorf("invalid raft-addr %q: masters dial it, so it needs a reachable host", cfg.RaftAddr))
+		}
+		if cfg.DataDir == "" {
+			check(fmt.Errorf("raft-addr needs a data-dir to keep the raft log in"))
+		}
+		// every master bootstraps from its own list, so each has to name all
+		peers := common.ParseAddrs(cfg.RaftPeers)
+		if !slices.Contains(peers, cfg.RaftAddr) {
+			check(fmt.Errorf("invalid raft-peers %q: must list every master, raft-addr %s included", cfg.RaftPeers, cfg.RaftAddr))
+		}
+		if len(slices.Compact(slices.Sorted(slices.Values(peers)))) != len(peers) {
+			check(fmt.Errorf("invalid raft-peers %q: must not repeat a master", cfg.RaftPeers))
+		}
+	}
+	if cfg.IsMaster && cfg.SSHAddr != "" {
+		// registered keys can only be added over an authenticated api
+		apiAuthenticated := cfg.APITokenFilePath != "" || cfg.APITLSClientCAPath != ""
+		if cfg.SSHAuthorizedKeysPath != "" {
+			check(validateReadable("ssh-authorized-keys-file", cfg.SSHAuthorizedKeysPath))
+		} else if !apiAuthenticated {
+			check(fmt.Errorf("ssh-addr needs an ssh-authorized-keys-file, or an api-token-file or " +
+				"api-tls-client-ca-file to register keys over"))
+		}
+		if cfg.SSHHostKeyFilePath == "" && cfg.DataDir == "" {
+			check(fmt.Errorf("ssh-addr needs an ssh-host-key-file or a data-dir to keep the host key in"))
+		}
+	}
+	if cfg.IsMaster && cfg.WebhookConfigFilePath != "" {
+		check(validateReadable("webhook-config-file", cfg.WebhookConfigFilePath))
+	}
+	if cfg.IsMaster {
+		check(validateAPIAuth(cfg))
+	}
+	if cfg.IsWorker {
+		check(validateCertificate(cfg.MultipassCertFilePath, cfg.MultipassKeyFilePath))
+	}
+
+	return errors.Join(errs...)
+}
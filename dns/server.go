@@ -0,0 +1,228 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/erayarslan/multiverse/agent"
+	"github.com/erayarslan/multiverse/cluster"
+
+	goDns "github.com/miekg/dns"
+)
+
+const (
+	sshService = "_ssh._tcp"
+	sshPort    = 22
+)
+
+type server struct {
+	clusterServer cluster.Server
+	udp           *goDns.Server
+	tcp           *goDns.Server
+	domain        string
+	ttl           uint32
+}
+
+type Server interface {
+	Serve() error
+	GracefulStop(timeout time.Duration) error
+}
+
+type record struct {
+	instance *agent.Instance
+	node     string
+}
+
+// Serve answers over udp and tcp until either fails or the server stops.
+func (s *server) Serve() error {
+	errs := make(chan error, 2)
+	go func() {
+		errs <- s.udp.ActivateAndServe()
+	}()
+	go func() {
+		errs <- s.tcp.ActivateAndServe()
+	}()
+	return <-errs
+}
+
+func (s *server) GracefulStop(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return errors.Join(s.udp.ShutdownContext(ctx), s.tcp.ShutdownContext(ctx))
+}
+
+// lookup returns the instances a name relative to the domain points at,
+// <instance> matching on every node and <instance>.<node> on one.
+func (s *server) lookup(name string) []record {
+	instanceName, nodeName, _ := strings.Cut(name, ".")
+
+	records := make([]record, 0)
+	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
+		if nodeName != "" && !strings.EqualFold(workerInfo.NodeName, nodeName) {
+			return true
+		}
+		for _, instance := range workerInfo.State.Instances {
+			if strings.EqualFold(instance.Name, instanceName) {
+				records = append(records, record{instance: instance, node: workerInfo.NodeName})
+			}
+		}
+		return true
+	})
+	return records
+}
+
+func (s *server) header(name string, rrtype uint16) goDns.RR_Header {
+	return goDns.RR_Header{Name: name, Rrtype: rrtype, Class: goDns.ClassINET, Ttl: s.ttl}
+}
+
+func (s *server) soa() goDns.RR {
+	return &goDns.SOA{
+		Hdr:     s.header(s.domain, goDns.TypeSOA),
+		Ns:      "ns." + s.domain,
+		Mbox:    "hostmaster." + s.domain,
+		Serial:  uint32(time.Now().Unix()),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  s.ttl,
+	}
+}
+
+func (s *server) fqdn(r record) string {
+	return strings.ToLower(fmt.Sprintf("%s.%s.%s", r.instance.Name, r.node, s.domain))
+}
+
+func (s *server) addresses(name string, records []record) []goDns.RR {
+	rrs := make([]goDns.RR, 0)
+	for _, r := range records {
+		for _, ip := range r.instance.Ipv4 {
+			if ip := net.ParseIP(ip).To4(); ip != nil {
+				rrs = append(rrs, &goDns.A{Hdr: s.header(name, goDns.TypeA), A: ip})
+			}
+		}
+	}
+	return rrs
+}
+
+func (s *server) texts(name string, records []record) []goDns.RR {
+	rrs := make([]goDns.RR, 0, len(records))
+	for _, r := range records {
+		rrs = append(rrs, &goDns.TXT{
+			Hdr: s.header(name, goDns.TypeTXT),
+			Txt: []string{"node=" + r.node, "state=" + r.instance.State, "image=" + r.instance.Image},
+		})
+	}
+	return rrs
+}
+
+// services points _ssh._tcp names at the per node name of each instance,
+// adding its addresses as extra records.
+func (s *server) services(name string, records []record) ([]goDns.RR, []goDns.RR) {
+	rrs := make([]goDns.RR, 0, len(records))
+	extra := make([]goDns.RR, 0)
+	for _, r := range records {
+		target := s.fqdn(r)
+		rrs = append(rrs, &goDns.SRV{Hdr: s.header(name, goDns.TypeSRV), Port: sshPort, Target: target})
+		extra = append(extra, s.addresses(target, []record{r})...)
+	}
+	return rrs, extra
+}
+
+func (s *server) answer(req *goDns.Msg) *goDns.Msg {
+	res := new(goDns.Msg)
+	res.SetReply(req)
+	res.Authoritative = true
+
+	// followers know no workers, failing lets resolvers ask the next master
+	if !s.clusterServer.IsLeader() {
+		res.SetRcode(req, goDns.RcodeServerFailure)
+		return res
+	}
+
+	question := req.Question[0]
+	name := strings.ToLower(question.Name)
+	if name == s.domain {
+		if question.Qtype == goDns.TypeSOA {
+			res.Answer = append(res.Answer, s.soa())
+		} else {
+			res.Ns = append(res.Ns, s.soa())
+		}
+		return res
+	}
+
+	relative := strings.TrimSuffix(name, "."+s.domain)
+	service := false
+	if rest, ok := strings.CutPrefix(relative, sshService+"."); ok {
+		relative, service = rest, true
+	}
+
+	records := s.lookup(relative)
+	if len(records) == 0 {
+		res.SetRcode(req, goDns.RcodeNameError)
+		res.Ns = append(res.Ns, s.soa())
+		return res
+	}
+
+	switch {
+	case service && question.Qtype == goDns.TypeSRV:
+		res.Answer, res.Extra = s.services(question.Name, records)
+	case !service && question.Qtype == goDns.TypeA:
+		res.Answer = s.addresses(question.Name, records)
+	case !service && question.Qtype == goDns.TypeTXT:
+		res.Answer = s.texts(question.Name, records)
+	}
+	if len(res.Answer) == 0 {
+		res.Ns = append(res.Ns, s.soa())
+	}
+	return res
+}
+
+func (s *server) handle(w goDns.ResponseWriter, req *goDns.Msg) {
+	var res *goDns.Msg
+	if len(req.Question) != 1 {
+		res = new(goDns.Msg)
+		res.SetRcode(req, goDns.RcodeFormatError)
+	} else {
+		res = s.answer(req)
+	}
+	_ = w.WriteMsg(res)
+}
+
+// refuse answers names outside the domain, master is no recursive resolver.
+func refuse(w goDns.ResponseWriter, req *goDns.Msg) {
+	res := new(goDns.Msg)
+	res.SetRcode(req, goDns.RcodeRefused)
+	_ = w.WriteMsg(res)
+}
+
+// NewServer serves A, TXT and _ssh._tcp SRV records of instances under
+// domain on udp and tcp at addr.
+func NewServer(addr string, domain string, ttl time.Duration, clusterServer cluster.Server) (Server, error) {
+	domain = goDns.Fqdn(strings.ToLower(domain))
+
+	packetConn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		_ = packetConn.Close()
+		return nil, err
+	}
+
+	s := &server{
+		clusterServer: clusterServer,
+		domain:        domain,
+		ttl:           uint32(ttl.Seconds()),
+	}
+	mux := goDns.NewServeMux()
+	mux.HandleFunc(domain, s.handle)
+	mux.HandleFunc(".", refuse)
+	s.udp = &goDns.Server{PacketConn: packetConn, Handler: mux}
+	s.tcp = &goDns.Server{Listener: lis, Handler: mux}
+	return s, nil
+}
@@ -0,0 +1,180 @@
+package dns
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/erayarslan/multiverse/agent"
+	"github.com/erayarslan/multiverse/cluster"
+
+	goDns "github.com/miekg/dns"
+)
+
+// fakeCluster serves workers, the rest of cluster.Server is left out.
+type fakeCluster struct {
+	cluster.Server
+	workers []*cluster.WorkerInfo
+	leader  bool
+}
+
+func (c *fakeCluster) IsLeader() bool {
+	return c.leader
+}
+
+func (c *fakeCluster) IterateWorkers(callback func(info *cluster.WorkerInfo) bool) {
+	for _, w := range c.workers {
+		if !callback(w) {
+			return
+		}
+	}
+}
+
+func worker(node string, instances ...*agent.Instance) *cluster.WorkerInfo {
+	return &cluster.WorkerInfo{NodeName: node, State: &cluster.State{Instances: instances}}
+}
+
+func rrStrings(rrs []goDns.RR) []string {
+	s := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		s = append(s, rr.String())
+	}
+	return s
+}
+
+func TestAnswer(t *testing.T) {
+	web := &agent.Instance{Name: "Web", State: "Running", Image: "24.04", Ipv4: []string{"10.0.0.2", "fd00::2"}}
+	webOther := &agent.Instance{Name: "web", State: "Stopped", Image: "22.04", Ipv4: []string{"10.0.1.2"}}
+	db := &agent.Instance{Name: "db", State: "Running", Image: "24.04"}
+
+	s := &server{
+		clusterServer: &fakeCluster{leader: true, workers: []*cluster.WorkerInfo{
+			worker("node1", web, db),
+			worker("Node2", webOther),
+		}},
+		domain: "mv.local.",
+		ttl:    30,
+	}
+
+	tests := []struct {
+		name       string
+		qname      string
+		wantAnswer []string
+		wantExtra  []string
+		wantRcode  int
+		qtype      uint16
+		wantSOA    bool
+	}{
+		{
+			name:  "a on every node",
+			qname: "web.mv.local.", qtype: goDns.TypeA,
+			wantAnswer: []string{
+				"web.mv.local.\t30\tIN\tA\t10.0.0.2",
+				"web.mv.local.\t30\tIN\tA\t10.0.1.2",
+			},
+		},
+		{
+			name:  "a on one node",
+			qname: "WEB.node2.mv.local.", qtype: goDns.TypeA,
+			wantAnswer: []string{"WEB.node2.mv.local.\t30\tIN\tA\t10.0.1.2"},
+		},
+		{
+			name:  "txt",
+			qname: "web.node1.mv.local.", qtype: goDns.TypeTXT,
+			wantAnswer: []string{"web.node1.mv.local.\t30\tIN\tTXT\t\"node=node1\" \"state=Running\" \"image=24.04\""},
+		},
+		{
+			name:  "ssh srv",
+			qname: "_ssh._tcp.web.mv.local.", qtype: goDns.TypeSRV,
+			wantAnswer: []string{
+				"_ssh._tcp.web.mv.local.\t30\tIN\tSRV\t0 0 22 web.node1.mv.local.",
+				"_ssh._tcp.web.mv.local.\t30\tIN\tSRV\t0 0 22 web.node2.mv.local.",
+			},
+			wantExtra: []string{
+				"web.node1.mv.local.\t30\tIN\tA\t10.0.0.2",
+				"web.node2.mv.local.\t30\tIN\tA\t10.0.1.2",
+			},
+		},
+		{
+			name:  "no address",
+			qname: "db.mv.local.", qtype: goDns.TypeA,
+			wantSOA: true,
+		},
+		{
+			name:  "unsupported type",
+			qname: "web.mv.local.", qtype: goDns.TypeAAAA,
+			wantSOA: true,
+		},
+		{
+			name:  "srv without service",
+			qname: "web.mv.local.", qtype: goDns.TypeSRV,
+			wantSOA: true,
+		},
+		{
+			name:  "unknown instance",
+			qname: "cache.mv.local.", qtype: goDns.TypeA,
+			wantRcode: goDns.RcodeNameError, wantSOA: true,
+		},
+		{
+			name:  "unknown node",
+			qname: "web.node3.mv.local.", qtype: goDns.TypeA,
+			wantRcode: goDns.RcodeNameError, wantSOA: true,
+		},
+		{
+			name:  "domain soa",
+			qname: "MV.local.", qtype: goDns.TypeSOA,
+		},
+		{
+			name:  "domain other type",
+			qname: "mv.local.", qtype: goDns.TypeA,
+			wantSOA: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := new(goDns.Msg)
+			req.SetQuestion(tt.qname, tt.qtype)
+
+			res := s.answer(req)
+
+			if res.Rcode != tt.wantRcode {
+				t.Errorf("rcode = %s, want %s", goDns.RcodeToString[res.Rcode], goDns.RcodeToString[tt.wantRcode])
+			}
+			if !res.Authoritative {
+				t.Errorf("answer not authoritative")
+			}
+			if tt.qtype == goDns.TypeSOA {
+				if len(res.Answer) != 1 || res.Answer[0].Header().Rrtype != goDns.TypeSOA {
+					t.Errorf("answer = %v, want the soa", res.Answer)
+				}
+				return
+			}
+			if got := rrStrings(res.Answer); !slices.Equal(got, tt.wantAnswer) {
+				t.Errorf("answer = %q, want %q", got, tt.wantAnswer)
+			}
+			if got := rrStrings(res.Extra); !slices.Equal(got, tt.wantExtra) {
+				t.Errorf("extra = %q, want %q", got, tt.wantExtra)
+			}
+			gotSOA := len(res.Ns) == 1 && res.Ns[0].Header().Rrtype == goDns.TypeSOA
+			if gotSOA != tt.wantSOA {
+				t.Errorf("authority = %v, want soa %v", res.Ns, tt.wantSOA)
+			}
+		})
+	}
+}
+
+func TestAnswerFollower(t *testing.T) {
+	s := &server{
+		clusterServer: &fakeCluster{workers: []*cluster.WorkerInfo{worker("node1", &agent.Instance{Name: "web"})}},
+		domain:        "mv.local.",
+		ttl:           30,
+	}
+	req := new(goDns.Msg)
+	req.SetQuestion("web.mv.local.", goDns.TypeA)
+
+	res := s.answer(req)
+
+	if res.Rcode != goDns.RcodeServerFailure || len(res.Answer) != 0 {
+		t.Errorf("follower answered %s %v, want SERVFAIL", goDns.RcodeToString[res.Rcode], res.Answer)
+	}
+}
@@ -0,0 +1,401 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erayarslan/multiverse/api"
+
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const metadataHeaderPrefix = "Grpc-Metadata-"
+
+var marshalOptions = protojson.MarshalOptions{EmitUnpopulated: true}
+
+type gateway struct {
+	conn           *grpc.ClientConn
+	listener       net.Listener
+	auth           *api.ServerAuth
+	httpServer     *http.Server
+	allowedOrigins []string
+	openAPI        []byte
+}
+
+type Gateway interface {
+	Serve() error
+	GracefulStop(timeout time.Duration) error
+}
+
+func (g *gateway) Serve() error {
+	if err := g.httpServer.Serve(g.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// GracefulStop waits for pending requests, streams and websocket shells end
+// once the api server stops.
+func (g *gateway) GracefulStop(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return errors.Join(g.httpServer.Shutdown(ctx), g.conn.Close())
+}
+
+func newMessage(desc protoreflect.MessageDescriptor) (proto.Message, error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+	if err != nil {
+		return nil, err
+	}
+	return messageType.New().Interface(), nil
+}
+
+func decodeBody(r *http.Request, rt *route, req proto.Message) error {
+	if rt.body == "" {
+		return nil
+	}
+
+	bytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes) == 0 {
+		return nil
+	}
+
+	if rt.body == "*" {
+		return protojson.Unmarshal(bytes, req)
+	}
+
+	message := req.ProtoReflect()
+	field := message.Descriptor().Fields().ByName(protoreflect.Name(rt.body))
+	return protojson.Unmarshal(bytes, message.Mutable(field).Message().Interface())
+}
+
+// decodeParams maps path and query parameters onto request fields by their
+// proto or json name and merges them over the decoded body.
+func decodeParams(r *http.Request, rt *route, req proto.Message) error {
+	fields := req.ProtoReflect().Descriptor().Fields()
+	params := make(map[string]any)
+
+	set := func(key string, values []string) error {
+		field := fields.ByName(protoreflect.Name(key))
+		if field == nil {
+			field = fields.ByJSONName(key)
+		}
+		if field == nil {
+			return fmt.Errorf("unknown parameter: %s", key)
+		}
+
+		if field.IsList() {
+			items := make([]string, 0, len(values))
+			for _, value := range values {
+				items = append(items, strings.Split(value, ",")...)
+			}
+			params[string(field.Name())] = items
+			return nil
+		}
+
+		if field.Kind() == protoreflect.BoolKind {
+			b, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return fmt.Errorf("invalid parameter %s: %v", key, err)
+			}
+			params[string(field.Name())] = b
+			return nil
+		}
+
+		params[string(field.Name())] = values[0]
+		return nil
+	}
+
+	for key, values := range r.URL.Query() {
+		if err := set(key, values); err != nil {
+			return err
+		}
+	}
+	for _, name := range rt.params {
+		if err := set(name, []string{r.PathValue(name)}); err != nil {
+			return err
+		}
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+
+	bytes, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	decoded := proto.Clone(req)
+	proto.Reset(decoded)
+	if err = protojson.Unmarshal(bytes, decoded); err != nil {
+		return err
+	}
+	proto.Merge(req, decoded)
+	return nil
+}
+
+func outgoingContext(r *http.Request) *http.Request {
+	md := metadata.MD{}
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		md.Set("authorization", authorization)
+	}
+	for key, values := range r.Header {
+		if strings.HasPrefix(key, metadataHeaderPrefix) {
+			md.Append(strings.TrimPrefix(key, metadataHeaderPrefix), values...)
+		}
+	}
+	return r.WithContext(metadata.NewOutgoingContext(r.Context(), md))
+}
+
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeMessage(w http.ResponseWriter, code int, message proto.Message) {
+	bytes, err := marshalOptions.Marshal(message)
+	if err != nil {
+		log.Printf("failed to marshal response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err = w.Write(bytes); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	s := status.Convert(err)
+	writeMessage(w, httpStatus(s.Code()), s.Proto())
+}
+
+func (g *gateway) unary(w http.ResponseWriter, r *http.Request, rt *route, req proto.Message) {
+	res, err := newMessage(rt.method.Output())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err = g.conn.Invoke(r.Context(), rt.fullMethod(), req, res); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, res)
+}
+
+func (g *gateway) serverStream(w http.ResponseWriter, r *http.Request, rt *route, req proto.Message) {
+	stream, err := g.conn.NewStream(r.Context(), &grpc.StreamDesc{ServerStreams: true}, rt.fullMethod())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err = stream.SendMsg(req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err = stream.CloseSend(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		res, err := newMessage(rt.method.Output())
+		if err != nil {
+			log.Printf("failed to create %s message: %v", rt.method.Name(), err)
+			return
+		}
+
+		if err = stream.RecvMsg(res); err != nil {
+			if errors.Is(err, io.EOF) || r.Context().Err() != nil {
+				return
+			}
+			// headers are already sent, so stream errors are reported in band
+			bytes, _ := marshalOptions.Marshal(status.Convert(err).Proto())
+			_, _ = fmt.Fprintf(w, "{\"error\":%s}\n", bytes)
+			return
+		}
+
+		bytes, err := marshalOptions.Marshal(res)
+		if err != nil {
+			log.Printf("failed to marshal %s stream: %v", rt.method.Name(), err)
+			return
+		}
+
+		if _, err = w.Write(append(bytes, '\n')); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// isJSON tells whether a request declares a json body, which html forms and
+// other simple cross site requests can not.
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func (g *gateway) handle(rt *route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rt.verb != http.MethodGet && !isJSON(r) {
+			writeMessage(w, http.StatusUnsupportedMediaType,
+				status.New(codes.InvalidArgument, "content type must be application/json").Proto())
+			return
+		}
+
+		req, err := newMessage(rt.method.Input())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if err = decodeBody(r, rt, req); err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid body: %v", err))
+			return
+		}
+		if err = decodeParams(r, rt, req); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, err.Error()))
+			return
+		}
+
+		r = outgoingContext(r)
+		if rt.method.IsStreamingServer() {
+			g.serverStream(w, r, rt, req)
+		} else {
+			g.unary(w, r, rt, req)
+		}
+	}
+}
+
+func (g *gateway) serveOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(g.openAPI); err != nil {
+		log.Printf("failed to write openapi document: %v", err)
+	}
+}
+
+// NewGateway serves the api at apiServerAddr over http, or https with the api
+// certificate, forwarding the Authorization header of callers so auth stays
+// with the api server.
+func NewGateway(addr string, apiServerAddr string, auth *api.ServerAuth, allowedOrigins []string) (Gateway, error) {
+	openAPI, err := OpenAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	dialOption, err := auth.LocalDialOption()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(apiServerAddr, dialOption)
+	if err != nil {
+		return nil, err
+	}
+
+	// callers send the api token, which must not cross the network in clear
+	tlsConfig, err := auth.GatewayTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil && auth.Authenticated() {
+		return nil, fmt.Errorf("gateway of an authenticated api needs api-tls-cert-file")
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		lis = tls.NewListener(lis, tlsConfig)
+	}
+
+	g := &gateway{
+		conn:           conn,
+		listener:       lis,
+		auth:           auth,
+		allowedOrigins: allowedOrigins,
+		openAPI:        openAPI,
+	}
+
+	mux := http.NewServeMux()
+	for _, rt := range routes {
+		mux.HandleFunc(rt.pattern(), g.handle(rt))
+	}
+	mux.HandleFunc("GET /v1/openapi.json", g.serveOpenAPI)
+	mux.Handle("GET /v1/instances/{instance_name}/shell", websocket.Server{Handshake: g.handshake, Handler: g.shell})
+
+	ui, err := uiFS()
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle("GET /", http.FileServerFS(ui))
+
+	g.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return g, nil
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type object = map[string]any
+
+type openAPI struct {
+	schemas object
+}
+
+func ref(desc protoreflect.MessageDescriptor) object {
+	return object{"$ref": "#/components/schemas/" + string(desc.FullName())}
+}
+
+func (o *openAPI) message(desc protoreflect.MessageDescriptor) object {
+	switch desc.FullName() {
+	case "google.protobuf.Timestamp":
+		return object{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration":
+		return object{"type": "string", "example": "120s"}
+	case "google.protobuf.Any":
+		return object{"type": "object"}
+	}
+
+	name := string(desc.FullName())
+	if _, ok := o.schemas[name]; ok {
+		return ref(desc)
+	}
+
+	properties := object{}
+	schema := object{"type": "object", "properties": properties}
+	o.schemas[name] = schema
+
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		properties[fields.Get(i).JSONName()] = o.field(fields.Get(i))
+	}
+
+	return ref(desc)
+}
+
+func (o *openAPI) kind(field protoreflect.FieldDescriptor) object {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return object{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return object{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return object{"type": "integer", "format": "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return object{"type": "string", "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return object{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		return object{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return object{"type": "number", "format": "double"}
+	case protoreflect.BytesKind:
+		return object{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return object{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return o.message(field.Message())
+	default:
+		return object{"type": "string"}
+	}
+}
+
+func (o *openAPI) field(field protoreflect.FieldDescriptor) object {
+	switch {
+	case field.IsMap():
+		return object{"type": "object", "additionalProperties": o.kind(field.MapValue())}
+	case field.IsList():
+		return object{"type": "array", "items": o.kind(field)}
+	default:
+		return o.kind(field)
+	}
+}
+
+func (o *openAPI) parameters(rt *route) []object {
+	parameters := make([]object, 0)
+	fields := rt.method.Input().Fields()
+
+	for _, name := range rt.params {
+		parameters = append(parameters, object{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   o.field(fields.ByName(protoreflect.Name(name))),
+		})
+	}
+
+	if rt.body == "*" {
+		return parameters
+	}
+
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if string(field.Name()) == rt.body || strings.Contains(rt.path, "{"+string(field.Name())+"}") {
+			continue
+		}
+		parameters = append(parameters, object{
+			"name":   string(field.Name()),
+			"in":     "query",
+			"schema": o.field(field),
+		})
+	}
+
+	return parameters
+}
+
+func (o *openAPI) operation(rt *route) object {
+	contentType := "application/json"
+	if rt.method.IsStreamingServer() {
+		contentType = "application/x-ndjson"
+	}
+
+	operation := object{
+		"operationId": string(rt.method.Name()),
+		"summary":     rt.summary,
+		"parameters":  o.parameters(rt),
+		"responses": object{
+			"200": object{
+				"description": "OK",
+				"content":     object{contentType: object{"schema": o.message(rt.method.Output())}},
+			},
+			"default": object{
+				"description": "Error",
+				"content": object{"application/json": object{
+					"schema": o.message((&status.Status{}).ProtoReflect().Descriptor()),
+				}},
+			},
+		},
+	}
+
+	switch rt.body {
+	case "":
+	case "*":
+		operation["requestBody"] = object{
+			"required": true,
+			"content":  object{"application/json": object{"schema": o.message(rt.method.Input())}},
+		}
+	default:
+		field := rt.method.Input().Fields().ByName(protoreflect.Name(rt.body))
+		operation["requestBody"] = object{
+			"required": true,
+			"content":  object{"application/json": object{"schema": o.field(field)}},
+		}
+	}
+
+	return operation
+}
+
+// OpenAPI describes the rest gateway routes and their protojson encoded
+// messages as an OpenAPI 3 document.
+func OpenAPI() ([]byte, error) {
+	o := &openAPI{schemas: object{}}
+
+	paths := object{}
+	for _, rt := range routes {
+		item, ok := paths[rt.path].(object)
+		if !ok {
+			item = object{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.verb)] = o.operation(rt)
+	}
+
+	return json.MarshalIndent(object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "multiverse",
+			"version": "v1",
+		},
+		"paths":      paths,
+		"components": object{"schemas": o.schemas},
+	}, "", "  ")
+}
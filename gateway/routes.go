@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/erayarslan/multiverse/api"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type route struct {
+	method  protoreflect.MethodDescriptor
+	verb    string
+	path    string
+	body    string
+	summary string
+	params  []string
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{(\w+)}`)
+
+// routes maps api.Rpc methods onto rest endpoints, body names the request
+// field the json body is decoded into, "*" being the whole request.
+var routes = []*route{
+	newRoute("nodes", http.MethodGet, "/v1/nodes", "", "List nodes"),
+	newRoute("instances", http.MethodGet, "/v1/instances", "", "List instances"),
+	newRoute("launch", http.MethodPost, "/v1/instances", "*", "Launch instance"),
+	newRoute("stop", http.MethodPost, "/v1/instances/{instance_name}/stop", "*", "Stop instance"),
+	newRoute("info", http.MethodGet, "/v1/info", "", "Get instance info"),
+	newRoute("taint", http.MethodPost, "/v1/nodes/{node_name}/taints", "taint", "Add taint to node"),
+	newRoute("untaint", http.MethodDelete, "/v1/nodes/{node_name}/taints/{key}", "", "Remove taint from node"),
+	newRoute("cordon", http.MethodPost, "/v1/nodes/{node_name}/cordon", "", "Exclude node from placement"),
+	newRoute("uncordon", http.MethodPost, "/v1/nodes/{node_name}/uncordon", "", "Include node in placement"),
+	newRoute("drain", http.MethodPost, "/v1/nodes/{node_name}/drain", "*", "Drain node"),
+	newRoute("watch", http.MethodGet, "/v1/events", "", "Watch cluster events as newline delimited json"),
+	newRoute("webhook_deliveries", http.MethodGet, "/v1/webhook-deliveries", "", "List recent webhook deliveries"),
+	newRoute("history", http.MethodGet, "/v1/history", "", "Query metrics history"),
+	newRoute("ssh_info", http.MethodGet, "/v1/instances/{instance_name}/ssh-info", "",
+		"Get ssh user, address and key of instance, as <instance> or <instance>.<node>"),
+	newRoute("version", http.MethodGet, "/v1/version", "", "Get master version and protocol"),
+	newRoute("keys", http.MethodGet, "/v1/keys", "", "List registered ssh keys"),
+	newRoute("add_key", http.MethodPost, "/v1/keys", "*", "Register ssh key and push it to running instances"),
+	newRoute("remove_key", http.MethodDelete, "/v1/keys/{name}", "", "Remove ssh key and revoke it on running instances"),
+	newRoute("push_keys", http.MethodPost, "/v1/keys/push", "*", "Push registered ssh keys to running instances"),
+}
+
+func newRoute(name string, verb string, path string, body string, summary string) *route {
+	method := api.File_api_api_proto.Services().ByName("Rpc").Methods().ByName(protoreflect.Name(name))
+	if method == nil {
+		panic("unknown rpc method: " + name)
+	}
+
+	params := make([]string, 0)
+	for _, match := range pathParamRegexp.FindAllStringSubmatch(path, -1) {
+		params = append(params, match[1])
+	}
+
+	return &route{
+		method:  method,
+		verb:    verb,
+		path:    path,
+		body:    body,
+		summary: summary,
+		params:  params,
+	}
+}
+
+func (r *route) fullMethod() string {
+	return "/" + string(r.method.Parent().FullName()) + "/" + string(r.method.Name())
+}
+
+func (r *route) pattern() string {
+	return r.verb + " " + r.path
+}
@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/erayarslan/multiverse/api"
+	"github.com/erayarslan/multiverse/common"
+
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultShellWidth    = 80
+	defaultShellHeight   = 24
+	shellProtocol        = "multiverse.shell"
+	bearerProtocolPrefix = "bearer."
+)
+
+// allowedOrigin admits pages served by the gateway itself and the allowed
+// origins, clients sending no origin not being browsers.
+func (g *gateway) allowedOrigin(origin string, host string) bool {
+	if origin == "" || slices.Contains(g.allowedOrigins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == host
+}
+
+// handshake admits shells from allowed origins carrying the api token. Since
+// browsers can not set websocket headers, they offer the shell protocol next
+// to bearer.<base64url token>, and only the shell protocol is echoed.
+func (g *gateway) handshake(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if !g.allowedOrigin(origin, r.Host) {
+		return fmt.Errorf("origin not allowed: %s", origin)
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	protocols := config.Protocol
+	config.Protocol = nil
+	for _, protocol := range protocols {
+		switch {
+		case protocol == shellProtocol:
+			config.Protocol = []string{shellProtocol}
+		case !ok && strings.HasPrefix(protocol, bearerProtocolPrefix):
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(protocol, bearerProtocolPrefix))
+			if err == nil {
+				token, ok = string(decoded), true
+			}
+		}
+	}
+	if !g.auth.ValidToken(token) {
+		return fmt.Errorf("shell needs the api token of master")
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func queryInt(values map[string][]string, key string, fallback int) int {
+	if v, ok := values[key]; ok && len(v) > 0 {
+		if i, err := strconv.Atoi(v[0]); err == nil && i > 0 {
+			return i
+		}
+	}
+	return fallback
+}
+
+// shell bridges a websocket carrying protojson encoded ShellRequest and
+// ShellReply messages onto the api shell stream. Every request sent to the
+// api carries the last known window size since the agent treats a size
+// change as a resize.
+func (g *gateway) shell(ws *websocket.Conn) {
+	r := outgoingContext(ws.Request())
+	instanceName := r.PathValue("instance_name")
+	query := r.URL.Query()
+	width := int64(queryInt(query, "width", defaultShellWidth))
+	height := int64(queryInt(query, "height", defaultShellHeight))
+
+	ctx := metadata.AppendToOutgoingContext(r.Context(),
+		"instanceName", instanceName,
+		"width", strconv.FormatInt(width, 10),
+		"height", strconv.FormatInt(height, 10),
+	)
+
+	stream, err := api.NewRpcClient(g.conn).Shell(ctx)
+	if err != nil {
+		log.Printf("failed to open shell of instance %s: %v", instanceName, err)
+		return
+	}
+
+	go func() {
+		defer func() {
+			if err := stream.CloseSend(); err != nil {
+				log.Printf("failed to close send: %v", err)
+			}
+		}()
+
+		for {
+			var message string
+			if err := websocket.Message.Receive(ws, &message); err != nil {
+				return
+			}
+
+			req := &common.ShellRequest{}
+			if err := protojson.Unmarshal([]byte(message), req); err != nil {
+				log.Printf("failed to decode shell request: %v", err)
+				continue
+			}
+			if req.Width > 0 && req.Height > 0 {
+				width, height = req.Width, req.Height
+			}
+
+			if err := stream.Send(&common.ShellRequest{InBuffer: req.InBuffer, Width: width, Height: height}); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = common.ListenBidiClient(stream, func(res *common.ShellReply) error {
+		bytes, err := protojson.Marshal(res)
+		if err != nil {
+			return err
+		}
+		return websocket.Message.Send(ws, string(bytes))
+	})
+	if err != nil && r.Context().Err() == nil {
+		log.Printf("failed to listen shell of instance %s: %v", instanceName, err)
+		bytes, _ := protojson.Marshal(&common.ShellReply{ErrBuffer: []byte(err.Error() + "\r\n")})
+		_ = websocket.Message.Send(ws, string(bytes))
+	}
+}
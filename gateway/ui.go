@@ -0,0 +1,13 @@
+package gateway
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+func uiFS() (fs.FS, error) {
+	return fs.Sub(uiFiles, "ui")
+}
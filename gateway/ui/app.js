@@ -0,0 +1,240 @@
+"use strict";
+
+const GiB = 1024 * 1024 * 1024;
+
+const $ = (id) => document.getElementById(id);
+
+function gib(bytes) {
+  return (Number(bytes || 0) / GiB).toFixed(1) + "GiB";
+}
+
+function cell(row, text) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  row.appendChild(td);
+  return td;
+}
+
+function button(td, text, onClick) {
+  const b = document.createElement("button");
+  b.textContent = text;
+  b.addEventListener("click", onClick);
+  td.appendChild(b);
+}
+
+// the api token lives in session storage, sent as bearer token and, since
+// browsers can not set websocket headers, as websocket protocol of the shell.
+function token() {
+  return sessionStorage.getItem("token") || "";
+}
+
+function headers() {
+  const h = {"Content-Type": "application/json"};
+  if (token()) {
+    h.Authorization = `Bearer ${token()}`;
+  }
+  return h;
+}
+
+async function request(method, path, body) {
+  const res = await fetch(path, {
+    method,
+    headers: headers(),
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  const json = await res.json();
+  if (!res.ok) {
+    throw new Error(json.message || res.statusText);
+  }
+  return json;
+}
+
+function renderNodes(nodes) {
+  const tbody = $("nodes");
+  tbody.replaceChildren();
+  for (const n of nodes) {
+    const row = document.createElement("tr");
+    const r = n.resource || {};
+    const status = (n.ready ? "Ready" : "NotReady") + (n.cordoned ? ",Cordoned" : "");
+    cell(row, n.name);
+    cell(row, status).className = n.ready ? "" : "not-ready";
+    cell(row, `${r.cpu?.committed ?? 0}/${r.cpu?.allocatable ?? 0}`);
+    cell(row, `${gib(r.memory?.committed)}/${gib(r.memory?.allocatable)}`);
+    cell(row, `${gib(r.disk?.committed)}/${gib(r.disk?.allocatable)}`);
+    cell(row, Object.entries(n.labels || {}).map(([k, v]) => `${k}=${v}`).join(","));
+    cell(row, n.lastSync ? new Date(n.lastSync).toLocaleString() : "-");
+    tbody.appendChild(row);
+  }
+}
+
+function renderInstances(instances) {
+  const tbody = $("instances");
+  tbody.replaceChildren();
+  for (const i of instances) {
+    const row = document.createElement("tr");
+    const name = i.instance.name;
+    // names repeat across nodes, so actions address the instance on its node
+    const qualified = `${name}.${i.nodeName}`;
+    cell(row, name);
+    cell(row, i.nodeName);
+    cell(row, i.instance.state);
+    cell(row, (i.instance.ipv4 || []).join(" "));
+    cell(row, i.instance.image);
+    const actions = cell(row, "");
+    if (i.instance.state === "Running") {
+      button(actions, "Shell", () => openShell(qualified));
+      button(actions, "Stop", async () => {
+        if (!confirm(`Stop ${name}?`)) {
+          return;
+        }
+        try {
+          await request("POST", `/v1/instances/${encodeURIComponent(qualified)}/stop`, {});
+          refresh();
+        } catch (e) {
+          alert(e.message);
+        }
+      });
+    }
+    tbody.appendChild(row);
+  }
+}
+
+async function refresh() {
+  try {
+    const [nodes, instances] = await Promise.all([
+      request("GET", "/v1/nodes"),
+      request("GET", "/v1/instances"),
+    ]);
+    renderNodes(nodes.nodes);
+    renderInstances(instances.instances);
+    $("status").textContent = "updated " + new Date().toLocaleTimeString();
+  } catch (e) {
+    $("status").textContent = e.message;
+  }
+}
+
+let refreshTimer;
+
+function scheduleRefresh() {
+  clearTimeout(refreshTimer);
+  refreshTimer = setTimeout(refresh, 250);
+}
+
+// watch streams cluster events as newline delimited json and refreshes on each.
+async function watch() {
+  try {
+    const res = await fetch("/v1/events", {headers: headers()});
+    if (!res.ok) {
+      const json = await res.json().catch(() => ({}));
+      throw new Error(`events: ${json.message || res.statusText}`);
+    }
+    const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffer = "";
+    for (;;) {
+      const {value, done} = await reader.read();
+      if (done) {
+        break;
+      }
+      buffer += value;
+      const lines = buffer.split("\n");
+      buffer = lines.pop();
+      if (lines.some((line) => line.trim() !== "")) {
+        scheduleRefresh();
+      }
+    }
+  } catch (e) {
+    $("status").textContent = e.message;
+  }
+  setTimeout(watch, 5000);
+}
+
+$("launch").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const form = new FormData(e.target);
+  const body = Object.fromEntries(form.entries());
+  body.numCores = Number(body.numCores);
+  try {
+    $("status").textContent = `launching ${body.instanceName}`;
+    await request("POST", "/v1/instances", body);
+    refresh();
+  } catch (err) {
+    alert(err.message);
+  }
+});
+
+function toBase64(text) {
+  const bytes = new TextEncoder().encode(text);
+  let binary = "";
+  for (const b of bytes) {
+    binary += String.fromCharCode(b);
+  }
+  return btoa(binary);
+}
+
+function fromBase64(text) {
+  return Uint8Array.from(atob(text), (c) => c.charCodeAt(0));
+}
+
+let shell;
+
+function closeShell() {
+  if (shell) {
+    shell.socket.close();
+    shell.terminal.dispose();
+    shell = undefined;
+  }
+  $("terminal-section").hidden = true;
+}
+
+function openShell(name) {
+  closeShell();
+  if (typeof Terminal === "undefined") {
+    alert("shell assets are missing, build with make ui-assets");
+    return;
+  }
+  $("terminal-section").hidden = false;
+  $("terminal-title").textContent = name;
+
+  const terminal = new Terminal({cursorBlink: true});
+  const fit = new FitAddon.FitAddon();
+  terminal.loadAddon(fit);
+  terminal.open($("terminal"));
+  fit.fit();
+
+  const scheme = location.protocol === "https:" ? "wss" : "ws";
+  const url = `${scheme}://${location.host}/v1/instances/${encodeURIComponent(name)}/shell` +
+    `?width=${terminal.cols}&height=${terminal.rows}`;
+  const protocols = ["multiverse.shell"];
+  if (token()) {
+    protocols.push("bearer." + toBase64(token()).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, ""));
+  }
+  const socket = new WebSocket(url, protocols);
+
+  socket.onmessage = (e) => {
+    const reply = JSON.parse(e.data);
+    if (reply.outBuffer) {
+      terminal.write(fromBase64(reply.outBuffer));
+    }
+    if (reply.errBuffer) {
+      terminal.write(fromBase64(reply.errBuffer));
+    }
+  };
+  socket.onclose = () => terminal.write("\r\n[connection closed]\r\n");
+
+  terminal.onData((data) => socket.send(JSON.stringify({inBuffer: toBase64(data)})));
+  terminal.onResize(({cols, rows}) => socket.send(JSON.stringify({width: cols, height: rows})));
+
+  shell = {socket, terminal, fit};
+}
+
+$("terminal-close").addEventListener("click", closeShell);
+$("token").value = token();
+$("token").addEventListener("change", (e) => {
+  sessionStorage.setItem("token", e.target.value.trim());
+  refresh();
+});
+window.addEventListener("resize", () => shell && shell.fit.fit());
+
+refresh();
+watch();
+setInterval(refresh, 15000);
@@ -0,0 +1,309 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/erayarslan/multiverse/agent"
+	"github.com/erayarslan/multiverse/cluster"
+	"github.com/erayarslan/multiverse/common"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	infoTimeout  = 5 * time.Second
+	persistEvery = 10
+)
+
+type history struct {
+	clusterServer cluster.Server
+	nodes         map[string]*ring[*common.NodeSample]
+	instances     map[string]*ring[*common.InstanceSample]
+	lastCPUTimes  map[string]*common.CPUTimes
+	path          string
+	interval      time.Duration
+	size          int
+	mu            sync.RWMutex
+}
+
+type History interface {
+	Run()
+	Query(start time.Time, end time.Time, nodeName string, instanceName string) *common.History
+}
+
+type sampledWorker struct {
+	agentClient agent.Client
+	sample      *common.NodeSample
+}
+
+func instanceKey(nodeName string, instanceName string) string {
+	return nodeName + "/" + instanceName
+}
+
+func cpuPercent(prev *common.CPUTimes, curr *common.CPUTimes) float64 {
+	if prev == nil || curr == nil {
+		return 0
+	}
+	busy := func(t *common.CPUTimes) float64 {
+		return t.User + t.Nice + t.System + t.Irq + t.Softirq + t.Steal
+	}
+	total := func(t *common.CPUTimes) float64 {
+		return busy(t) + t.Idle + t.Iowait
+	}
+	deltaTotal := total(curr) - total(prev)
+	if deltaTotal <= 0 {
+		return 0
+	}
+	return (busy(curr) - busy(prev)) / deltaTotal * 100
+}
+
+func nodeSample(now *timestamppb.Timestamp, workerInfo *cluster.WorkerInfo) *common.NodeSample {
+	resource := workerInfo.State.GetResource()
+	sample := &common.NodeSample{
+		Time:        now,
+		NodeName:    workerInfo.NodeName,
+		MemoryTotal: resource.GetMemory().GetTotal(),
+		MemoryUsed:  resource.GetMemory().GetTotal() - resource.GetMemory().GetAvailable(),
+		DiskTotal:   resource.GetDisk().GetTotal(),
+		DiskUsed:    resource.GetDisk().GetTotal() - resource.GetDisk().GetAvailable(),
+		Instances:   int32(len(workerInfo.State.Instances)),
+	}
+	if total := resource.GetCpu().GetTotal(); total > 0 {
+		sample.CpuPercent = float64(total-resource.GetCpu().GetAvailable()) / float64(total) * 100
+	}
+	return sample
+}
+
+func (h *history) instanceSamples(now *timestamppb.Timestamp, w *sampledWorker) []*common.InstanceSample {
+	ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+	defer cancel()
+
+	info, err := w.agentClient.Info(ctx, &common.GetInfoRequest{})
+	if err != nil {
+		log.Printf("failed to sample info of node %s: %v", w.sample.NodeName, err)
+		return nil
+	}
+
+	samples := make([]*common.InstanceSample, 0, len(info.Instances))
+	for _, instance := range info.Instances {
+		samples = append(samples, &common.InstanceSample{
+			Time:         now,
+			NodeName:     w.sample.NodeName,
+			InstanceName: instance.Name,
+			MemoryUsage:  instance.GetMemoryUsage(),
+			MemoryTotal:  instance.GetMemoryTotal(),
+			DiskUsage:    instance.GetDiskUsage(),
+			DiskTotal:    instance.GetDiskTotal(),
+			Load1:        instance.GetLoad().GetLoad1(),
+			CpuPercent: cpuPercent(h.lastCPUTimes[instanceKey(w.sample.NodeName, instance.Name)],
+				instance.GetCpuTimes()),
+		})
+		h.lastCPUTimes[instanceKey(w.sample.NodeName, instance.Name)] = instance.GetCpuTimes()
+	}
+	return samples
+}
+
+func (h *history) sample() {
+	now := timestamppb.Now()
+
+	workers := make([]*sampledWorker, 0)
+	h.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
+		workers = append(workers, &sampledWorker{
+			agentClient: workerInfo.AgentClient,
+			sample:      nodeSample(now, workerInfo),
+		})
+		return true
+	})
+
+	instances := make([]*common.InstanceSample, 0)
+	for _, w := range workers {
+		instances = append(instances, h.instanceSamples(now, w)...)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, w := range workers {
+		h.pushNode(w.sample)
+	}
+	for _, sample := range instances {
+		h.pushInstance(sample)
+	}
+	h.prune(now.AsTime())
+}
+
+func (h *history) pushNode(sample *common.NodeSample) {
+	r, ok := h.nodes[sample.NodeName]
+	if !ok {
+		r = newRing[*common.NodeSample](h.size)
+		h.nodes[sample.NodeName] = r
+	}
+	r.push(sample)
+}
+
+func (h *history) pushInstance(sample *common.InstanceSample) {
+	key := instanceKey(sample.NodeName, sample.InstanceName)
+	r, ok := h.instances[key]
+	if !ok {
+		r = newRing[*common.InstanceSample](h.size)
+		h.instances[key] = r
+	}
+	r.push(sample)
+}
+
+func (h *history) prune(now time.Time) {
+	retention := h.interval * time.Duration(h.size)
+	for key, r := range h.nodes {
+		if last, ok := r.last(); !ok || now.Sub(last.Time.AsTime()) > retention {
+			delete(h.nodes, key)
+		}
+	}
+	for key, r := range h.instances {
+		if last, ok := r.last(); !ok || now.Sub(last.Time.AsTime()) > retention {
+			delete(h.instances, key)
+			delete(h.lastCPUTimes, key)
+		}
+	}
+}
+
+func inRange(t *timestamppb.Timestamp, start time.Time, end time.Time) bool {
+	at := t.AsTime()
+	return (start.IsZero() || !at.Before(start)) && (end.IsZero() || !at.After(end))
+}
+
+func (h *history) Query(start time.Time, end time.Time, nodeName string, instanceName string) *common.History {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := &common.History{
+		Nodes:     make([]*common.NodeSample, 0),
+		Instances: make([]*common.InstanceSample, 0),
+	}
+
+	if instanceName == "" {
+		for name, r := range h.nodes {
+			if nodeName != "" && name != nodeName {
+				continue
+			}
+			for _, sample := range r.values() {
+				if inRange(sample.Time, start, end) {
+					result.Nodes = append(result.Nodes, sample)
+				}
+			}
+		}
+	}
+
+	for _, r := range h.instances {
+		last, ok := r.last()
+		if !ok || (nodeName != "" && last.NodeName != nodeName) ||
+			(instanceName != "" && last.InstanceName != instanceName) {
+			continue
+		}
+		for _, sample := range r.values() {
+			if inRange(sample.Time, start, end) {
+				result.Instances = append(result.Instances, sample)
+			}
+		}
+	}
+
+	sort.SliceStable(result.Nodes, func(i, j int) bool {
+		return result.Nodes[i].Time.AsTime().Before(result.Nodes[j].Time.AsTime())
+	})
+	sort.SliceStable(result.Instances, func(i, j int) bool {
+		return result.Instances[i].Time.AsTime().Before(result.Instances[j].Time.AsTime())
+	})
+
+	return result
+}
+
+func (h *history) load() error {
+	if h.path == "" {
+		return nil
+	}
+
+	bytes, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	persisted := &common.History{}
+	if err = proto.Unmarshal(bytes, persisted); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sample := range persisted.Nodes {
+		h.pushNode(sample)
+	}
+	for _, sample := range persisted.Instances {
+		h.pushInstance(sample)
+	}
+	return nil
+}
+
+func (h *history) persist() error {
+	if h.path == "" {
+		return nil
+	}
+
+	bytes, err := proto.Marshal(h.Query(time.Time{}, time.Time{}, "", ""))
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := h.path + ".tmp"
+	if err = os.WriteFile(tmp, bytes, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+func (h *history) Run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for i := 1; ; i++ {
+		<-ticker.C
+		h.sample()
+		if i%persistEvery == 0 {
+			if err := h.persist(); err != nil {
+				log.Printf("failed to persist history: %v", err)
+			}
+		}
+	}
+}
+
+func NewHistory(clusterServer cluster.Server, interval time.Duration, size int, path string) (History, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("history size must be greater than zero: %d", size)
+	}
+	h := &history{
+		clusterServer: clusterServer,
+		nodes:         make(map[string]*ring[*common.NodeSample]),
+		instances:     make(map[string]*ring[*common.InstanceSample]),
+		lastCPUTimes:  make(map[string]*common.CPUTimes),
+		path:          path,
+		interval:      interval,
+		size:          size,
+	}
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
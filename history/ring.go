@@ -0,0 +1,41 @@
+package history
+
+type ring[T any] struct {
+	items []T
+	next  int
+	full  bool
+}
+
+func (r *ring[T]) push(item T) {
+	r.items[r.next] = item
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring[T]) values() []T {
+	if !r.full {
+		values := make([]T, r.next)
+		copy(values, r.items[:r.next])
+		return values
+	}
+
+	values := make([]T, 0, len(r.items))
+	values = append(values, r.items[r.next:]...)
+	return append(values, r.items[:r.next]...)
+}
+
+func (r *ring[T]) last() (T, bool) {
+	var zero T
+	if !r.full && r.next == 0 {
+		return zero, false
+	}
+	return r.items[(r.next-1+len(r.items))%len(r.items)], true
+}
+
+func newRing[T any](size int) *ring[T] {
+	return &ring[T]{
+		items: make([]T, size),
+	}
+}
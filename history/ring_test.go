@@ -0,0 +1,51 @@
+package history
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		name   string
+		pushed []int
+		want   []int
+		size   int
+	}{
+		{name: "empty", size: 3, pushed: nil, want: []int{}},
+		{name: "partial", size: 3, pushed: []int{1, 2}, want: []int{1, 2}},
+		{name: "full", size: 3, pushed: []int{1, 2, 3}, want: []int{1, 2, 3}},
+		{name: "wrapped", size: 3, pushed: []int{1, 2, 3, 4, 5}, want: []int{3, 4, 5}},
+		{name: "wrapped twice", size: 2, pushed: []int{1, 2, 3, 4, 5}, want: []int{4, 5}},
+		{name: "single", size: 1, pushed: []int{1, 2}, want: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRing[int](tt.size)
+			for _, item := range tt.pushed {
+				r.push(item)
+			}
+
+			if got := r.values(); !slices.Equal(got, tt.want) {
+				t.Errorf("values() = %v, want %v", got, tt.want)
+			}
+
+			last, ok := r.last()
+			if wantOk := len(tt.want) > 0; ok != wantOk {
+				t.Fatalf("last() ok = %v, want %v", ok, wantOk)
+			}
+			if ok && last != tt.want[len(tt.want)-1] {
+				t.Errorf("last() = %d, want %d", last, tt.want[len(tt.want)-1])
+			}
+		})
+	}
+}
+
+func TestNewHistoryRejectsSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		if _, err := NewHistory(nil, 0, size, ""); err == nil {
+			t.Errorf("NewHistory(size=%d) expected error", size)
+		}
+	}
+}
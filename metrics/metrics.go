@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "multiverse"
+
+var (
+	GrpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Total number of handled grpc requests.",
+	}, []string{"server", "method", "code"})
+
+	GrpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Duration of handled grpc requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"server", "method"})
+
+	ShellSessions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "shell_sessions",
+		Help:      "Number of active shell sessions.",
+	}, []string{"component"})
+
+	SyncStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_streams",
+		Help:      "Number of connected worker sync streams on master.",
+	})
+
+	SyncReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_reconnects_total",
+		Help:      "Total number of worker sync stream reconnects to master.",
+	})
+)
+
+func observe(server string, method string, start time.Time, err error) {
+	GrpcRequests.WithLabelValues(server, method, status.Code(err).String()).Inc()
+	GrpcRequestDuration.WithLabelValues(server, method).Observe(time.Since(start).Seconds())
+}
+
+func UnaryServerInterceptor(server string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		observe(server, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+func StreamServerInterceptor(server string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(server, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func ServerOptions(server string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(server)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(server)),
+	}
+}
+
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
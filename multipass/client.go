@@ -4,11 +4,16 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 
 	"github.com/erayarslan/multiverse/common"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
 type client struct {
@@ -22,11 +27,28 @@ type instance struct {
 	Ipv4  []string
 }
 
+// Allocated is what an instance was given at launch, kept while it is
+// stopped or suspended unlike the runtime figures of info.
+type Allocated struct {
+	Memory uint64
+	Disk   uint64
+	CPU    int32
+}
+
 type Client interface {
 	List(ctx context.Context) ([]*instance, error)
+	Allocated(ctx context.Context, instanceName string) (*Allocated, error)
 	SSHInfo(ctx context.Context, instanceName string) (*SSHInfo, error)
 	Launch(ctx context.Context, request *common.LaunchRequest) (*common.LaunchReply, error)
 	Info(ctx context.Context, request *common.GetInfoRequest) (*common.GetInfoReply, error)
+	Authenticate(ctx context.Context, passphrase string) error
+	Stop(ctx context.Context, request *common.StopRequest) (*common.StopReply, error)
+	Suspend(ctx context.Context, request *common.SuspendRequest) (*common.SuspendReply, error)
+}
+
+func IsUntrusted(err error) bool {
+	s, ok := status.FromError(err)
+	return ok && s.Code() == codes.Unauthenticated
 }
 
 func (s InstanceStatus_Status) ToString() string {
@@ -73,16 +95,16 @@ func (c *client) Info(ctx context.Context, _ *common.GetInfoRequest) (*common.Ge
 			getInfoReply.Instances = append(getInfoReply.Instances, &common.GetInfoInstance{
 				Id:                instanceExtraInfo.InstanceInfo.Id,
 				Name:              detail.Name,
-				MemoryUsage:       instanceExtraInfo.InstanceInfo.MemoryUsage,
-				MemoryTotal:       detail.MemoryTotal,
-				DiskUsage:         instanceExtraInfo.InstanceInfo.DiskUsage,
-				DiskTotal:         detail.DiskTotal,
-				Load:              instanceExtraInfo.InstanceInfo.Load,
-				CpuTimes:          instanceExtraInfo.InstanceInfo.CpuTimes,
-				CpuCount:          detail.CpuCount,
+				MemoryUsage:       parseUint64(instanceExtraInfo.InstanceInfo.MemoryUsage),
+				MemoryTotal:       parseUint64(detail.MemoryTotal),
+				DiskUsage:         parseUint64(instanceExtraInfo.InstanceInfo.DiskUsage),
+				DiskTotal:         parseUint64(detail.DiskTotal),
+				Load:              parseLoad(instanceExtraInfo.InstanceInfo.Load),
+				CpuTimes:          parseCPUTimes(instanceExtraInfo.InstanceInfo.CpuTimes),
+				CpuCount:          parseInt32(detail.CpuCount),
 				ImageRelease:      instanceExtraInfo.InstanceInfo.ImageRelease,
 				CurrentRelease:    instanceExtraInfo.InstanceInfo.CurrentRelease,
-				Uptime:            instanceExtraInfo.InstanceInfo.Uptime,
+				UptimeSeconds:     parseUptime(instanceExtraInfo.InstanceInfo.Uptime),
 				CreationTimestamp: instanceExtraInfo.InstanceInfo.CreationTimestamp,
 			})
 		}
@@ -98,10 +120,11 @@ func (c *client) Launch(ctx context.Context, request *common.LaunchRequest) (*co
 	}
 
 	_, err = common.ExecuteOnceWithBidiClient(stream, &LaunchRequest{
-		InstanceName: request.InstanceName,
-		NumCores:     request.NumCores,
-		MemSize:      request.MemSize,
-		DiskSpace:    request.DiskSpace,
+		InstanceName:      request.InstanceName,
+		NumCores:          request.NumCores,
+		MemSize:           request.MemSize,
+		DiskSpace:         request.DiskSpace,
+		CloudInitUserData: request.CloudInitUserData,
 	})
 	if err != nil {
 		return nil, err
@@ -110,6 +133,39 @@ func (c *client) Launch(ctx context.Context, request *common.LaunchRequest) (*co
 	return &common.LaunchReply{}, nil
 }
 
+func (c *client) Stop(ctx context.Context, request *common.StopRequest) (*common.StopReply, error) {
+	stream, err := c.rpcClient.Stop(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = common.ExecuteWithBidiClient(stream, &StopRequest{
+		InstanceNames: &InstanceNames{InstanceName: []string{request.InstanceName}},
+		ForceStop:     request.Force,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.StopReply{}, nil
+}
+
+func (c *client) Suspend(ctx context.Context, request *common.SuspendRequest) (*common.SuspendReply, error) {
+	stream, err := c.rpcClient.Suspend(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = common.ExecuteWithBidiClient(stream, &SuspendRequest{
+		InstanceNames: &InstanceNames{InstanceName: []string{request.InstanceName}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.SuspendReply{}, nil
+}
+
 func (c *client) List(ctx context.Context) ([]*instance, error) {
 	stream, err := c.rpcClient.List(ctx)
 	if err != nil {
@@ -146,6 +202,55 @@ func (c *client) List(ctx context.Context) ([]*instance, error) {
 	return instances, nil
 }
 
+func (c *client) get(ctx context.Context, key string) (string, error) {
+	stream, err := c.rpcClient.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := common.ExecuteOnceWithBidiClient(stream, &GetRequest{Key: key})
+	if err != nil {
+		return "", err
+	}
+	return res.Value, nil
+}
+
+// Allocated reads the cpus, memory and disk settings of an instance.
+func (c *client) Allocated(ctx context.Context, instanceName string) (*Allocated, error) {
+	settings := make(map[string]string, 3)
+	for _, setting := range []string{"cpus", "memory", "disk"} {
+		value, err := c.get(ctx, fmt.Sprintf("local.%s.%s", instanceName, setting))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s of %s: %w", setting, instanceName, err)
+		}
+		settings[setting] = value
+	}
+
+	cpus, err := strconv.ParseInt(strings.TrimSpace(settings["cpus"]), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cpus of %s: %w", instanceName, err)
+	}
+	memory, err := common.ParseSize(settings["memory"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory of %s: %w", instanceName, err)
+	}
+	disk, err := common.ParseSize(settings["disk"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid disk of %s: %w", instanceName, err)
+	}
+	return &Allocated{CPU: int32(cpus), Memory: memory, Disk: disk}, nil
+}
+
+func (c *client) Authenticate(ctx context.Context, passphrase string) error {
+	stream, err := c.rpcClient.Authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = common.ExecuteOnceWithBidiClient(stream, &AuthenticateRequest{Passphrase: passphrase})
+	return err
+}
+
 func (c *client) SSHInfo(ctx context.Context, instanceName string) (*SSHInfo, error) {
 	stream, err := c.rpcClient.SshInfo(ctx)
 	if err != nil {
@@ -166,6 +271,14 @@ func (c *client) SSHInfo(ctx context.Context, instanceName string) (*SSHInfo, er
 }
 
 func NewClient(target string, multipassCertFilePath string, multipassKeyFilePath string) (Client, error) {
+	generated, err := EnsureCertificate(multipassCertFilePath, multipassKeyFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error while generating multipass client certificate: %w", err)
+	}
+	if generated {
+		log.Printf("generated multipass client certificate: %s", multipassCertFilePath)
+	}
+
 	multipassCertificate, err := tls.LoadX509KeyPair(multipassCertFilePath, multipassKeyFilePath)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,123 @@
+package multipass
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/erayarslan/multiverse/common"
+)
+
+// userHz is the kernel clock tick rate cpu times in /proc/stat are reported in.
+const userHz = 100
+
+var uptimeUnits = map[string]int64{
+	"year":   365 * 24 * 60 * 60,
+	"week":   7 * 24 * 60 * 60,
+	"day":    24 * 60 * 60,
+	"hour":   60 * 60,
+	"minute": 60,
+	"second": 1,
+}
+
+func parseUint64(value string) *uint64 {
+	v, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func parseInt32(value string) *int32 {
+	v, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return nil
+	}
+	i := int32(v)
+	return &i
+}
+
+func parseLoad(value string) *common.Load {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return nil
+	}
+
+	loads := make([]float64, 3)
+	for i := range loads {
+		load, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil
+		}
+		loads[i] = load
+	}
+
+	return &common.Load{Load1: loads[0], Load5: loads[1], Load15: loads[2]}
+}
+
+// parseCPUTimes parses the aggregate cpu line of /proc/stat, e.g.
+// "cpu  2255 34 2290 22625563 6290 127 456 0 0 0".
+func parseCPUTimes(value string) *common.CPUTimes {
+	fields := strings.Fields(value)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "cpu") {
+		fields = fields[1:]
+	}
+	if len(fields) < 4 {
+		return nil
+	}
+
+	times := make([]float64, 8)
+	for i := range times {
+		if i >= len(fields) {
+			break
+		}
+		ticks, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil
+		}
+		times[i] = ticks / userHz
+	}
+
+	return &common.CPUTimes{
+		User:    times[0],
+		Nice:    times[1],
+		System:  times[2],
+		Idle:    times[3],
+		Iowait:  times[4],
+		Irq:     times[5],
+		Softirq: times[6],
+		Steal:   times[7],
+	}
+}
+
+// parseUptime accepts either plain seconds or the "uptime -p" form,
+// e.g. "2 days, 3 hours, 4 minutes".
+func parseUptime(value string) *int64 {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "up ")
+	if value == "" {
+		return nil
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		s := int64(seconds)
+		return &s
+	}
+
+	var total int64
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			return nil
+		}
+		n, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil
+		}
+		unit, ok := uptimeUnits[strings.TrimSuffix(fields[1], "s")]
+		if !ok {
+			return nil
+		}
+		total += n * unit
+	}
+
+	return &total
+}
@@ -3,190 +3,118 @@ package role
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
-	"strconv"
-	"strings"
-	"text/tabwriter"
 
-	"github.com/erayarslan/multiverse/common"
-
-	"github.com/erayarslan/multiverse/api"
+	"github.com/erayarslan/multiverse/cli"
 	"github.com/erayarslan/multiverse/config"
 )
 
 type client struct {
-	apiClient api.Client
-	cfg       *config.Config
-	doneCh    chan struct{}
+	ctx    context.Context
+	cfg    *config.Config
+	doneCh chan struct{}
+	cancel context.CancelFunc
 }
 
-func (c *client) instances() {
-	getInstancesReply, err := c.apiClient.Instances(context.Background())
-	if err != nil {
-		log.Fatalf("error while instances: %v", err)
-	}
-
-	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
-
-	fs := "%s\t%s\t%s\t%s\t%s\n"
-	_, err = fmt.Fprintf(w, fs, "Node Name", "Instance Name", "State", "IPv4", "Image")
-	if err != nil {
-		return
-	}
-	for _, n := range getInstancesReply.Instances {
-		_, err = fmt.Fprintf(w, fs, n.NodeName, n.Instance.Name, n.Instance.State, strings.Join(n.Instance.Ipv4, "\n"), n.Instance.Image)
-		if err != nil {
-			return
-		}
-	}
-
-	err = w.Flush()
-	if err != nil {
-		return
+func optional(args []string, flag string, value string) []string {
+	if value == "" {
+		return args
 	}
+	return append(args, flag, value)
 }
 
-func (c *client) nodes() {
-	getNodesReply, err := c.apiClient.Nodes(context.Background())
-	if err != nil {
-		log.Fatalf("error while nodes: %v", err)
-	}
-
-	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
-
-	fs := "%s\t%s\t%s\t%s\t%s\t%s\n"
-	_, err = fmt.Fprintf(w, fs, "Node Name", "IPv4", "Cpu", "Mem", "Disk", "Last Sync")
-	if err != nil {
-		return
-	}
-	for _, n := range getNodesReply.Nodes {
-		_, err = fmt.Fprintf(w, fs,
-			n.Name,
-			strings.Join(n.Ipv4, "\n"),
-			fmt.Sprintf("%d", n.Resource.Cpu.Available),
-			fmt.Sprintf("%vGb", n.Resource.Memory.Available/1024/1024/1024),
-			fmt.Sprintf("%vGb", n.Resource.Disk.Available/1024/1024/1024),
-			n.LastSync.AsTime().Format("2006-01-02 15:04:05 MST"),
-		)
-		if err != nil {
-			return
+// args maps the legacy -client flags onto the cli command tree.
+func (c *client) args() []string {
+	cfg := c.cfg
+	switch {
+	case cfg.Instances:
+		return []string{"instances", "list"}
+	case cfg.Nodes:
+		return []string{"nodes", "list", "-o", "wide"}
+	case cfg.Shell:
+		return []string{"instances", "shell", cfg.ShellInstanceName}
+	case cfg.Launch:
+		args := []string{
+			"instances", "launch", cfg.LaunchInstanceName,
+			"-cpus", cfg.LaunchNumCores, "-memory", cfg.LaunchMemSize, "-disk", cfg.LaunchDiskSpace,
 		}
-	}
-
-	err = w.Flush()
-	if err != nil {
-		return
+		args = optional(args, "-node-selector", cfg.LaunchNodeSelector)
+		args = optional(args, "-affinity", cfg.LaunchAffinity)
+		args = optional(args, "-anti-affinity", cfg.LaunchAntiAffinity)
+		return optional(args, "-tolerations", cfg.LaunchTolerations)
+	case cfg.Info:
+		return []string{"instances", "info", "-o", "wide"}
+	case cfg.Taint:
+		return []string{"nodes", "taint", cfg.TargetNodeName, cfg.TaintSpec}
+	case cfg.Untaint:
+		return []string{"nodes", "untaint", cfg.TargetNodeName, cfg.TaintSpec}
+	case cfg.Cordon:
+		return []string{"nodes", "cordon", cfg.TargetNodeName}
+	case cfg.Uncordon:
+		return []string{"nodes", "uncordon", cfg.TargetNodeName}
+	case cfg.Drain:
+		return []string{
+			"nodes", "drain", cfg.TargetNodeName,
+			"-action", cfg.DrainAction, "-timeout", cfg.DrainTimeout.String(), fmt.Sprintf("-force=%t", cfg.DrainForce),
+		}
+	case cfg.WebhookDeliveries:
+		return []string{"webhooks", "deliveries"}
+	case cfg.Watch:
+		return optional([]string{"events", "watch"}, "-types", cfg.WatchTypes)
+	case cfg.Top:
+		return []string{"top", "-sort", cfg.TopSort, "-interval", cfg.TopInterval.String()}
+	case cfg.Export == "ansible-inventory":
+		return []string{"export", cfg.Export, "-format", cfg.ExportFormat}
+	case cfg.Export != "":
+		return []string{"export", cfg.Export}
+	default:
+		return nil
 	}
 }
 
-func (c *client) info() {
-	getInfoReply, err := c.apiClient.Info(context.Background())
-	if err != nil {
-		log.Fatalf("error while info: %v", err)
+func (c *client) Execute() error {
+	args := c.args()
+	if args == nil {
+		c.doneCh <- struct{}{}
+		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
-
-	fs := "%s\t%s\t%s\t%s\t%s\t%s\n"
-	_, err = fmt.Fprintf(w, fs, "Node Name", "Instance Name", "Cpu", "Load", "Disk", "Memory")
-	if err != nil {
-		return
+	globals := [][2]string{
+		{"api-server-addr", c.cfg.APIServerAddr},
+		{"context", c.cfg.Context},
+		{"client-config", c.cfg.ClientConfigFilePath},
 	}
-	for _, n := range getInfoReply.Instances {
-		diskUsage, err := strconv.ParseFloat(n.Instance.DiskUsage, 64)
-		if err != nil {
-			log.Fatalf("error while parsing disk usage: %v", err)
-		}
-		diskTotal, err := strconv.ParseFloat(n.Instance.DiskTotal, 64)
-		if err != nil {
-			log.Fatalf("error while parsing disk total: %v", err)
-		}
-		memoryUsage, err := strconv.ParseFloat(n.Instance.MemoryUsage, 64)
-		if err != nil {
-			log.Fatalf("error while parsing memory usage: %v", err)
-		}
-		memoryTotal, err := strconv.ParseFloat(n.Instance.MemoryTotal, 64)
-		if err != nil {
-			log.Fatalf("error while parsing memory total: %v", err)
-		}
-
-		_, err = fmt.Fprintf(w, fs, n.NodeName, n.Instance.Name, n.Instance.CpuCount, n.Instance.Load,
-			fmt.Sprintf("%.1fGiB out of %.1fGiB", diskUsage/1024/1024/1024, diskTotal/1024/1024/1024),
-			fmt.Sprintf("%.1fGiB out of %.1fGiB", memoryUsage/1024/1024/1024, memoryTotal/1024/1024/1024),
-		)
-		if err != nil {
-			return
+	for _, global := range globals {
+		if config.IsSet(global[0]) {
+			args = append(args, "-"+global[0], global[1])
 		}
 	}
 
-	err = w.Flush()
-	if err != nil {
-		return
-	}
-}
-
-func (c *client) shell() {
-	err := c.apiClient.Shell(context.Background(), c.cfg.ShellInstanceName)
-	if err != nil {
-		log.Fatalf("error while shell: %v", err)
-	}
-}
-
-func (c *client) launch() {
-	// parse c.cfg.LaunchNumCores to int32
-	numCores, err := strconv.ParseInt(c.cfg.LaunchNumCores, 10, 32)
-	if err != nil {
-		log.Fatalf("error while parsing num cores: %v", err)
-	}
-
-	_, err = c.apiClient.Launch(context.Background(), &common.LaunchRequest{
-		InstanceName: c.cfg.LaunchInstanceName,
-		NumCores:     int32(numCores),
-		MemSize:      c.cfg.LaunchMemSize,
-		DiskSpace:    c.cfg.LaunchDiskSpace,
-	})
-	if err != nil {
-		log.Fatalf("error while launch: %v", err)
-	}
+	go func() {
+		if code := cli.Run(c.ctx, args, os.Stdout, os.Stderr); code != 0 {
+			os.Exit(code)
+		}
+		c.doneCh <- struct{}{}
+	}()
 
-	log.Printf("instance %s launched", c.cfg.LaunchInstanceName)
+	return nil
 }
 
-func (c *client) Execute() error {
-	log.Printf("api server addr: %s", c.cfg.APIServerAddr)
-
-	var err error
-	c.apiClient, err = api.NewClient(c.cfg.APIServerAddr)
-	if err != nil {
-		log.Fatalf("error while creating api client: %v", err)
-	}
-
-	switch {
-	case c.cfg.Instances:
-		c.instances()
-	case c.cfg.Nodes:
-		c.nodes()
-	case c.cfg.Shell:
-		c.shell()
-	case c.cfg.Launch:
-		c.launch()
-	case c.cfg.Info:
-		c.info()
-	}
-
-	c.doneCh <- struct{}{}
-
-	return nil
+func (c *client) Reload(_ *config.Config) ([]string, error) {
+	return nil, nil
 }
 
 func (c *client) GracefulShutdown() error {
-	return c.apiClient.Close()
+	c.cancel()
+	return nil
 }
 
 func NewClient(cfg *config.Config, doneCh chan struct{}) Role {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &client{
 		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
 		doneCh: doneCh,
 	}
 }
@@ -1,28 +1,108 @@
 package role
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
 
 	"github.com/erayarslan/multiverse/api"
 	"github.com/erayarslan/multiverse/cluster"
+	"github.com/erayarslan/multiverse/common"
 	"github.com/erayarslan/multiverse/config"
+	"github.com/erayarslan/multiverse/dns"
+	"github.com/erayarslan/multiverse/gateway"
+	"github.com/erayarslan/multiverse/history"
+	"github.com/erayarslan/multiverse/scheduler"
+	"github.com/erayarslan/multiverse/ssh"
+	"github.com/erayarslan/multiverse/webhook"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type master struct {
-	cfg *config.Config
+	cfg           *config.Config
+	notifier      webhook.Notifier
+	clusterServer cluster.Server
+	apiServer     api.Server
+	gateway       gateway.Gateway
+	dnsServer     dns.Server
+	sshServer     ssh.Server
+}
+
+// raft returns the raft config of the master, nil without a raft addr.
+func (c *master) raft() *cluster.Raft {
+	if c.cfg.RaftAddr == "" {
+		return nil
+	}
+	return &cluster.Raft{Addr: c.cfg.RaftAddr, Peers: common.ParseAddrs(c.cfg.RaftPeers)}
+}
+
+func (c *master) apiAuth() (*api.ServerAuth, error) {
+	token, err := api.LoadToken(c.cfg.APITokenFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ServerAuth{
+		Token:        token,
+		CertFile:     c.cfg.APITLSCertFilePath,
+		KeyFile:      c.cfg.APITLSKeyFilePath,
+		ClientCAFile: c.cfg.APITLSClientCAPath,
+	}, nil
 }
 
 func (c *master) Execute() error {
-	log.Printf("master addr: %s", c.cfg.MasterAddr)
+	// a comma separated list names every master, this one listening on the first
+	masterAddr := common.ParseAddrs(c.cfg.MasterAddr)[0]
+	apiServerAddr := common.ParseAddrs(c.cfg.APIServerAddr)[0]
+	log.Printf("master addr: %s", masterAddr)
 
-	clusterServer, err := cluster.NewServer(c.cfg.MasterAddr)
+	raft := c.raft()
+	if raft != nil {
+		log.Printf("raft addr: %s, peers: %s", raft.Addr, strings.Join(raft.Peers, ","))
+	}
+
+	clusterServer, err := cluster.NewServer(masterAddr, c.cfg.DataDir, raft)
 	if err != nil {
 		log.Fatalf("error while creating master: %v", err)
 	}
+	c.clusterServer = clusterServer
+
+	prometheus.MustRegister(cluster.NewCollector(clusterServer))
+
+	sinks, err := webhook.LoadSinks(c.cfg.WebhookConfigFilePath)
+	if err != nil {
+		log.Fatalf("error while loading webhook sinks: %v", err)
+	}
 
-	log.Printf("api server addr: %s", c.cfg.APIServerAddr)
+	c.notifier = webhook.NewNotifier(sinks, filepath.Join(c.cfg.DataDir, "webhook-dead-letter.jsonl"))
+	events, _ := clusterServer.SubscribeDropping(c.notifier.Dropped)
+	go c.notifier.Run(events)
+
+	historyPath := ""
+	if c.cfg.HistoryPersist {
+		historyPath = filepath.Join(c.cfg.DataDir, "history.pb")
+	}
 
-	apiServer, err := api.NewServer(c.cfg.APIServerAddr, clusterServer)
+	metricsHistory, err := history.NewHistory(clusterServer, c.cfg.HistoryInterval, c.cfg.HistorySize, historyPath)
+	if err != nil {
+		log.Fatalf("error while loading metrics history: %v", err)
+	}
+	go metricsHistory.Run()
+
+	log.Printf("api server addr: %s", apiServerAddr)
+
+	apiAuth, err := c.apiAuth()
+	if err != nil {
+		log.Fatalf("error while loading api auth: %v", err)
+	}
+	if !apiAuth.Authenticated() {
+		log.Printf("api server accepts every caller, set -api-token-file or -api-tls-client-ca-file to require auth")
+	}
+
+	c.apiServer, err = api.NewServer(apiServerAddr, apiAuth, clusterServer, scheduler.NewScheduler(clusterServer),
+		c.notifier, metricsHistory)
 	if err != nil {
 		log.Fatalf("error while creating api server: %v", err)
 	}
@@ -36,16 +116,127 @@ func (c *master) Execute() error {
 
 	go func() {
 		var err error
-		if err = apiServer.Serve(); err != nil {
+		if err = c.apiServer.Serve(); err != nil {
 			log.Fatalf("error while serving master: %v", err)
 		}
 	}()
 
+	if c.cfg.GatewayAddr != "" {
+		log.Printf("gateway addr: %s", c.cfg.GatewayAddr)
+
+		c.gateway, err = gateway.NewGateway(c.cfg.GatewayAddr, apiServerAddr, apiAuth,
+			common.ParseAddrs(c.cfg.GatewayOrigins))
+		if err != nil {
+			log.Fatalf("error while creating gateway: %v", err)
+		}
+
+		go func() {
+			if err := c.gateway.Serve(); err != nil {
+				log.Fatalf("error while serving gateway: %v", err)
+			}
+		}()
+	}
+
+	if c.cfg.DNSAddr != "" {
+		log.Printf("dns addr: %s, domain: %s", c.cfg.DNSAddr, c.cfg.DNSDomain)
+
+		c.dnsServer, err = dns.NewServer(c.cfg.DNSAddr, c.cfg.DNSDomain, c.cfg.DNSTTL, clusterServer)
+		if err != nil {
+			log.Fatalf("error while creating dns server: %v", err)
+		}
+
+		go func() {
+			if err := c.dnsServer.Serve(); err != nil {
+				log.Fatalf("error while serving dns: %v", err)
+			}
+		}()
+	}
+
+	if c.cfg.SSHAddr != "" {
+		log.Printf("ssh addr: %s", c.cfg.SSHAddr)
+
+		keys, err := ssh.LoadAuthorizedKeys(c.cfg.SSHAuthorizedKeysPath)
+		if err != nil {
+			log.Fatalf("error while loading ssh authorized keys: %v", err)
+		}
+
+		hostKeyPath := c.cfg.SSHHostKeyFilePath
+		if hostKeyPath == "" {
+			hostKeyPath = filepath.Join(c.cfg.DataDir, "ssh_host_ed25519_key")
+		}
+
+		c.sshServer, err = ssh.NewServer(c.cfg.SSHAddr, hostKeyPath, keys, clusterServer)
+		if err != nil {
+			log.Fatalf("error while creating ssh server: %v", err)
+		}
+
+		go func() {
+			if err := c.sshServer.Serve(); err != nil {
+				log.Fatalf("error while serving ssh: %v", err)
+			}
+		}()
+	}
+
 	return nil
 }
 
+// Reload applies each reloadable flag on its own, reporting the ones that
+// failed next to the ones applied.
+func (c *master) Reload(cfg *config.Config) ([]string, error) {
+	reloaded := make([]string, 0)
+	errs := make([]error, 0)
+
+	sinks, err := webhook.LoadSinks(cfg.WebhookConfigFilePath)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("webhook-config-file: %w", err))
+	} else {
+		c.notifier.SetSinks(sinks)
+		log.Printf("reloaded %d webhook sinks", len(sinks))
+		reloaded = append(reloaded, "webhook-config-file")
+	}
+
+	if c.sshServer != nil {
+		keys, err := ssh.LoadAuthorizedKeys(cfg.SSHAuthorizedKeysPath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ssh-authorized-keys-file: %w", err))
+		} else {
+			c.sshServer.SetAuthorizedKeys(keys)
+			log.Printf("reloaded %d ssh authorized keys", len(keys))
+			reloaded = append(reloaded, "ssh-authorized-keys-file")
+		}
+	}
+
+	return reloaded, errors.Join(errs...)
+}
+
+// GracefulShutdown stops the api server first so shell users are notified,
+// then the gateway, dns and ssh servers and finally the cluster server.
 func (c *master) GracefulShutdown() error {
-	return nil
+	timeout := c.cfg.ShutdownTimeout
+
+	log.Printf("stopping api server")
+	c.apiServer.GracefulStop(timeout)
+
+	var err error
+	if c.gateway != nil {
+		log.Printf("stopping gateway")
+		err = c.gateway.GracefulStop(timeout)
+	}
+
+	if c.dnsServer != nil {
+		log.Printf("stopping dns server")
+		err = errors.Join(err, c.dnsServer.GracefulStop(timeout))
+	}
+
+	if c.sshServer != nil {
+		log.Printf("stopping ssh server")
+		err = errors.Join(err, c.sshServer.GracefulStop(timeout))
+	}
+
+	log.Printf("stopping master")
+	c.clusterServer.GracefulStop(timeout)
+
+	return err
 }
 
 func NewMaster(cfg *config.Config) Role {
@@ -1,6 +1,11 @@
 package role
 
+import "github.com/erayarslan/multiverse/config"
+
 type Role interface {
 	Execute() error
+	// Reload applies cfg in place and returns the flag names it applied, also
+	// when it fails to apply others.
+	Reload(cfg *config.Config) ([]string, error)
 	GracefulShutdown() error
 }
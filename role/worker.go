@@ -1,17 +1,37 @@
 package role
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 
 	"github.com/erayarslan/multiverse/agent"
 	"github.com/erayarslan/multiverse/cluster"
+	"github.com/erayarslan/multiverse/common"
 	"github.com/erayarslan/multiverse/config"
 	"github.com/erayarslan/multiverse/multipass"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type worker struct {
 	cfg           *config.Config
 	clusterClient cluster.Client
+	agentServer   agent.Server
+	state         agent.State
+}
+
+// reloadableWorkerFlags are applied in place by Reload.
+var reloadableWorkerFlags = []string{
+	"labels",
+	"cpu-overcommit-ratio",
+	"memory-overcommit-ratio",
+	"disk-overcommit-ratio",
+	"reserved-cpu",
+	"reserved-memory",
+	"reserved-disk",
+	"state-interval",
 }
 
 func (c *worker) Execute() error {
@@ -26,21 +46,37 @@ func (c *worker) Execute() error {
 		log.Fatalf("error while creating multipass client: %v", err)
 	}
 
-	state := agent.NewState(multipassClient)
-	go state.Run()
+	if err = c.trust(multipassClient); err != nil {
+		log.Fatalf("error while authenticating multipass client: %v", err)
+	}
+
+	allocation, err := allocation(c.cfg)
+	if err != nil {
+		log.Fatalf("error while parsing allocation: %v", err)
+	}
+
+	c.state = agent.NewState(multipassClient, allocation, c.cfg.StateInterval)
+	go c.state.Run()
+	prometheus.MustRegister(agent.NewCollector(c.state))
 
-	server, err := agent.NewServer(c.cfg.MultipassProxyBind, multipassClient, state)
+	c.agentServer, err = agent.NewServer(c.cfg.MultipassProxyBind, multipassClient, c.state)
 	if err != nil {
 		log.Fatalf("error while creating multipass proxy: %v", err)
 	}
 
-	c.clusterClient, err = cluster.NewClient(c.cfg.MasterAddr, c.cfg.NodeName, server, multipassClient, state)
+	labels, err := common.ParseLabels(c.cfg.Labels)
+	if err != nil {
+		log.Fatalf("error while parsing labels: %v", err)
+	}
+
+	c.clusterClient, err = cluster.NewClient(c.cfg.MasterAddr, c.cfg.NodeName, labels, c.agentServer, multipassClient, c.state,
+		cluster.Backoff{Max: c.cfg.ReconnectMaxBackoff, MaxAttempts: c.cfg.ReconnectMaxAttempts})
 	if err != nil {
 		log.Fatalf("error while creating worker: %v", err)
 	}
 
 	go func() {
-		if err := server.Serve(); err != nil {
+		if err := c.agentServer.Serve(); err != nil {
 			log.Fatalf("error while serving multipass proxy: %v", err)
 		}
 	}()
@@ -55,8 +91,84 @@ func (c *worker) Execute() error {
 	return nil
 }
 
+func allocation(cfg *config.Config) (agent.Allocation, error) {
+	reservedMemory, err := common.ParseSize(cfg.ReservedMemory)
+	if err != nil {
+		return agent.Allocation{}, fmt.Errorf("reserved-memory: %w", err)
+	}
+
+	reservedDisk, err := common.ParseSize(cfg.ReservedDisk)
+	if err != nil {
+		return agent.Allocation{}, fmt.Errorf("reserved-disk: %w", err)
+	}
+
+	return agent.Allocation{
+		CPUOvercommitRatio:    cfg.CPUOvercommitRatio,
+		MemoryOvercommitRatio: cfg.MemoryOvercommitRatio,
+		DiskOvercommitRatio:   cfg.DiskOvercommitRatio,
+		ReservedCPU:           int32(cfg.ReservedCPU),
+		ReservedMemory:        reservedMemory,
+		ReservedDisk:          reservedDisk,
+	}, nil
+}
+
+func (c *worker) trust(multipassClient multipass.Client) error {
+	ctx := context.Background()
+
+	_, err := multipassClient.List(ctx)
+	if err == nil {
+		return nil
+	}
+	if !multipass.IsUntrusted(err) {
+		return fmt.Errorf("multipass is not reachable: %w", err)
+	}
+
+	if c.cfg.MultipassPassphrase == "" {
+		return fmt.Errorf("multipass client certificate is not trusted, set -multipass-passphrase: %w", err)
+	}
+
+	log.Printf("multipass client certificate is not trusted, authenticating with passphrase")
+
+	if err = multipassClient.Authenticate(ctx, c.cfg.MultipassPassphrase); err != nil {
+		return err
+	}
+
+	_, err = multipassClient.List(ctx)
+	return err
+}
+
+// Reload parses every reloadable flag before applying any, so a bad value
+// leaves the worker as it was.
+func (c *worker) Reload(cfg *config.Config) ([]string, error) {
+	labels, err := common.ParseLabels(cfg.Labels)
+	if err != nil {
+		err = fmt.Errorf("labels: %w", err)
+	}
+	allocation, allocationErr := allocation(cfg)
+	if err = errors.Join(err, allocationErr); err != nil {
+		return nil, err
+	}
+
+	c.state.SetAllocation(allocation)
+	c.state.SetInterval(cfg.StateInterval)
+	if err = c.clusterClient.SetLabels(labels); err != nil {
+		// labels are kept and sent again once the worker reconnects
+		log.Printf("error while sending labels, master gets them on reconnect: %v", err)
+	}
+
+	return reloadableWorkerFlags, nil
+}
+
+// GracefulShutdown leaves the cluster first so master stops placing
+// instances here, then notifies shell users and stops the agent server.
 func (c *worker) GracefulShutdown() error {
-	return c.clusterClient.Close()
+	log.Printf("leaving master")
+	err := c.clusterClient.Close()
+
+	log.Printf("stopping agent server")
+	c.agentServer.GracefulStop(c.cfg.ShutdownTimeout)
+
+	return err
 }
 
 func NewWorker(cfg *config.Config) Role {
@@ -0,0 +1,202 @@
+package scheduler
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/erayarslan/multiverse/cluster"
+	"github.com/erayarslan/multiverse/common"
+)
+
+const preferNoSchedulePenalty = 1
+
+type scheduler struct {
+	clusterServer cluster.Server
+}
+
+type Scheduler interface {
+	Schedule(req *common.LaunchRequest) (*cluster.WorkerInfo, error)
+}
+
+type request struct {
+	*common.LaunchRequest
+	memory uint64
+	disk   uint64
+}
+
+type candidate struct {
+	workerInfo *cluster.WorkerInfo
+	score      float64
+}
+
+func newRequest(req *common.LaunchRequest) (*request, error) {
+	r := &request{LaunchRequest: req}
+
+	var err error
+	if req.MemSize != "" {
+		if r.memory, err = common.ParseSize(req.MemSize); err != nil {
+			return nil, err
+		}
+	}
+	if req.DiskSpace != "" {
+		if r.disk, err = common.ParseSize(req.DiskSpace); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func matchesSelector(labels map[string]string, selector map[string]string) error {
+	for key, value := range selector {
+		if v, ok := labels[key]; !ok || v != value {
+			return fmt.Errorf("label %s=%s not matched", key, value)
+		}
+	}
+	return nil
+}
+
+func instanceNodes(workers []*cluster.WorkerInfo) map[string]string {
+	nodes := make(map[string]string)
+	for _, workerInfo := range workers {
+		for _, instance := range workerInfo.State.Instances {
+			nodes[instance.Name] = workerInfo.NodeName
+		}
+	}
+	return nodes
+}
+
+func matchesAffinity(nodeName string, req *request, nodes map[string]string) error {
+	for _, instanceName := range req.Affinity {
+		if nodes[instanceName] != nodeName {
+			return fmt.Errorf("affinity to instance %s not satisfied", instanceName)
+		}
+	}
+	for _, instanceName := range req.AntiAffinity {
+		if nodes[instanceName] == nodeName {
+			return fmt.Errorf("anti affinity to instance %s not satisfied", instanceName)
+		}
+	}
+	return nil
+}
+
+func fits(workerInfo *cluster.WorkerInfo, req *request) (float64, error) {
+	resource := workerInfo.State.GetResource()
+
+	cpu := resource.GetCpu()
+	if cpu.GetAllocatable() > 0 && cpu.GetCommitted()+req.NumCores > cpu.GetAllocatable() {
+		return 0, fmt.Errorf("insufficient cpu")
+	}
+	memory := resource.GetMemory()
+	if memory.GetAllocatable() > 0 && memory.GetCommitted()+req.memory > memory.GetAllocatable() {
+		return 0, fmt.Errorf("insufficient memory")
+	}
+	disk := resource.GetDisk()
+	if disk.GetAllocatable() > 0 && disk.GetCommitted()+req.disk > disk.GetAllocatable() {
+		return 0, fmt.Errorf("insufficient disk")
+	}
+
+	if memory.GetAllocatable() == 0 {
+		return 0, nil
+	}
+
+	return float64(memory.GetAllocatable()-memory.GetCommitted()-req.memory) / float64(memory.GetAllocatable()), nil
+}
+
+func (s *scheduler) filter(workerInfo *cluster.WorkerInfo, req *request, nodes map[string]string) (*candidate, error) {
+	if workerInfo.Cordoned {
+		return nil, fmt.Errorf("node is cordoned")
+	}
+	if !workerInfo.Ready {
+		return nil, fmt.Errorf("node is not ready")
+	}
+
+	if err := matchesSelector(workerInfo.Labels, req.NodeSelector); err != nil {
+		return nil, err
+	}
+
+	if err := matchesAffinity(workerInfo.NodeName, req, nodes); err != nil {
+		return nil, err
+	}
+
+	score, err := fits(workerInfo, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, taint := range workerInfo.Taints {
+		if common.IsTolerated(taint, req.Tolerations) {
+			continue
+		}
+		if taint.Effect == common.TaintEffectNoSchedule {
+			return nil, fmt.Errorf("taint %s not tolerated", taint.Format())
+		}
+		score -= preferNoSchedulePenalty
+	}
+
+	return &candidate{workerInfo: workerInfo, score: score}, nil
+}
+
+// snapshot copies a worker while IterateWorkers holds the lock, sync streams
+// replacing its state, labels and taints meanwhile.
+func snapshot(workerInfo *cluster.WorkerInfo) *cluster.WorkerInfo {
+	copied := *workerInfo
+	copied.Labels = maps.Clone(workerInfo.Labels)
+	copied.Taints = slices.Clone(workerInfo.Taints)
+	return &copied
+}
+
+func (s *scheduler) Schedule(launchRequest *common.LaunchRequest) (*cluster.WorkerInfo, error) {
+	req, err := newRequest(launchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := make([]*cluster.WorkerInfo, 0)
+	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
+		workers = append(workers, snapshot(workerInfo))
+		return true
+	})
+	if len(workers) == 0 {
+		return nil, fmt.Errorf("no node available")
+	}
+
+	nodes := instanceNodes(workers)
+	if nodeName, ok := nodes[req.InstanceName]; ok {
+		return nil, fmt.Errorf("instance %s already exists on node: %s", req.InstanceName, nodeName)
+	}
+
+	candidates := make([]*candidate, 0, len(workers))
+	reasons := make([]string, 0, len(workers))
+	for _, workerInfo := range workers {
+		c, err := s.filter(workerInfo, req, nodes)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", workerInfo.NodeName, err))
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+
+	if len(candidates) == 0 {
+		sort.Strings(reasons)
+		return nil, fmt.Errorf("no node matches launch constraints: %s", strings.Join(reasons, ", "))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score == candidates[j].score {
+			return candidates[i].workerInfo.NodeName < candidates[j].workerInfo.NodeName
+		}
+		return candidates[i].score > candidates[j].score
+	})
+
+	return candidates[0].workerInfo, nil
+}
+
+func NewScheduler(clusterServer cluster.Server) Scheduler {
+	return &scheduler{
+		clusterServer: clusterServer,
+	}
+}
@@ -0,0 +1,105 @@
+package ssh
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/erayarslan/multiverse/metrics"
+
+	goSsh "golang.org/x/crypto/ssh"
+)
+
+// forwardedChannels are opened on the instance as asked, sessions covering
+// shells, exec and subsystems such as sftp.
+var forwardedChannels = map[string]bool{
+	"session":      true,
+	"direct-tcpip": true,
+}
+
+// refusedRequests would make the instance open channels back to the client.
+var refusedRequests = map[string]bool{
+	"auth-agent-req@openssh.com": true,
+	"x11-req":                    true,
+}
+
+func proxyChannel(newChannel goSsh.NewChannel, upstream goSsh.Conn) {
+	channelType := newChannel.ChannelType()
+	if !forwardedChannels[channelType] {
+		_ = newChannel.Reject(goSsh.UnknownChannelType, "unsupported channel type: "+channelType)
+		return
+	}
+
+	upstreamChannel, upstreamReqs, err := upstream.OpenChannel(channelType, newChannel.ExtraData())
+	if err != nil {
+		var openErr *goSsh.OpenChannelError
+		if errors.As(err, &openErr) {
+			_ = newChannel.Reject(openErr.Reason, openErr.Message)
+		} else {
+			_ = newChannel.Reject(goSsh.ConnectionFailed, err.Error())
+		}
+		return
+	}
+
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		_ = upstreamChannel.Close()
+		return
+	}
+
+	if channelType == "session" {
+		metrics.ShellSessions.WithLabelValues("ssh").Inc()
+		defer metrics.ShellSessions.WithLabelValues("ssh").Dec()
+	}
+
+	go func() {
+		forwardRequests(reqs, upstreamChannel, true)
+		// the client closed its side
+		_ = upstreamChannel.Close()
+	}()
+	go func() {
+		_, _ = io.Copy(upstreamChannel, channel)
+		_ = upstreamChannel.CloseWrite()
+	}()
+
+	// exit status and output have to reach the client before the channel closes
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		forwardRequests(upstreamReqs, channel, false)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(channel, upstreamChannel)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(channel.Stderr(), upstreamChannel.Stderr())
+	}()
+	wg.Wait()
+
+	if err = channel.CloseWrite(); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("failed to close ssh channel: %v", err)
+	}
+	_ = channel.Close()
+}
+
+// forwardRequests relays channel requests, answering with the reply of the
+// other side.
+func forwardRequests(reqs <-chan *goSsh.Request, channel goSsh.Channel, fromClient bool) {
+	for req := range reqs {
+		if fromClient && refusedRequests[req.Type] {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		ok, err := channel.SendRequest(req.Type, req.WantReply, req.Payload)
+		if err != nil {
+			ok = false
+		}
+		if req.WantReply {
+			_ = req.Reply(ok, nil)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erayarslan/multiverse/agent"
+	"github.com/erayarslan/multiverse/cluster"
+	"github.com/erayarslan/multiverse/common"
+
+	goSsh "golang.org/x/crypto/ssh"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	handshakeTimeout = 20 * time.Second
+	identityKey      = "identity"
+)
+
+// AuthorizedKeys maps marshaled public keys to the identity they belong to.
+type AuthorizedKeys map[string]string
+
+type server struct {
+	clusterServer cluster.Server
+	lis           net.Listener
+	keys          AuthorizedKeys
+	conns         map[*goSsh.ServerConn]struct{}
+	config        *goSsh.ServerConfig
+	wg            sync.WaitGroup
+	keysMu        sync.RWMutex
+	connsMu       sync.Mutex
+}
+
+type Server interface {
+	Serve() error
+	GracefulStop(timeout time.Duration) error
+	SetAuthorizedKeys(keys AuthorizedKeys)
+}
+
+// LoadAuthorizedKeys reads an openssh authorized_keys file, naming each
+// identity by the comment of its key. Without a path only keys registered
+// with master log in.
+func LoadAuthorizedKeys(path string) (AuthorizedKeys, error) {
+	if path == "" {
+		return AuthorizedKeys{}, nil
+	}
+	rest, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(AuthorizedKeys)
+	for len(bytes.TrimSpace(rest)) > 0 {
+		var key goSsh.PublicKey
+		var comment string
+		key, comment, _, rest, err = goSsh.ParseAuthorizedKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse authorized keys: %w", err)
+		}
+		if comment == "" {
+			comment = goSsh.FingerprintSHA256(key)
+		}
+		keys[string(key.Marshal())] = comment
+	}
+	return keys, nil
+}
+
+// loadHostKey reads the host key at path, generating an ed25519 key there
+// the first time so clients see the same host key across restarts.
+func loadHostKey(path string) (goSsh.Signer, error) {
+	pemBytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := goSsh.MarshalPrivateKey(key, "multiverse")
+		if err != nil {
+			return nil, err
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		pemBytes = pem.EncodeToMemory(block)
+		if err = os.WriteFile(path, pemBytes, 0o600); err != nil {
+			return nil, err
+		}
+		log.Printf("generated ssh host key: %s", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return goSsh.ParsePrivateKey(pemBytes)
+}
+
+func (s *server) SetAuthorizedKeys(keys AuthorizedKeys) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	s.keys = keys
+}
+
+// identity names the owner of key among the authorized keys file and the
+// keys registered with master.
+func (s *server) identity(key goSsh.PublicKey) (string, bool) {
+	s.keysMu.RLock()
+	identity, ok := s.keys[string(key.Marshal())]
+	s.keysMu.RUnlock()
+	if ok {
+		return identity, true
+	}
+
+	authorizedKey := strings.TrimSpace(string(goSsh.MarshalAuthorizedKey(key)))
+	for _, userKey := range s.clusterServer.UserKeys() {
+		if userKey.PublicKey == authorizedKey {
+			return userKey.Name, true
+		}
+	}
+	return "", false
+}
+
+func (s *server) authenticate(conn goSsh.ConnMetadata, key goSsh.PublicKey) (*goSsh.Permissions, error) {
+	identity, ok := s.identity(key)
+	if !ok {
+		return nil, fmt.Errorf("unknown public key for %s", conn.User())
+	}
+	return &goSsh.Permissions{Extensions: map[string]string{identityKey: identity}}, nil
+}
+
+// agentClientOf finds the agent running an instance, the user being
+// <instance> or <instance>.<node> when names repeat across nodes.
+func (s *server) agentClientOf(user string) (agent.Client, string, error) {
+	if !s.clusterServer.IsLeader() {
+		return nil, "", fmt.Errorf("master is not the leader")
+	}
+
+	instanceName, nodeName, _ := strings.Cut(user, ".")
+	var agentClient agent.Client
+	s.clusterServer.IterateWorkers(func(workerInfo *cluster.WorkerInfo) bool {
+		if nodeName != "" && workerInfo.NodeName != nodeName {
+			return true
+		}
+		for _, instance := range workerInfo.State.Instances {
+			if instance.Name == instanceName {
+				agentClient = workerInfo.AgentClient
+				return false
+			}
+		}
+		return true
+	})
+	if agentClient == nil {
+		return nil, "", fmt.Errorf("instance not found: %s", user)
+	}
+	return agentClient, instanceName, nil
+}
+
+// dial connects to the ssh server of an instance over the forward stream of
+// its agent, logging in with the multipass key of the node.
+func (s *server) dial(ctx context.Context, user string) (goSsh.Conn, <-chan goSsh.NewChannel, <-chan *goSsh.Request, error) {
+	agentClient, instanceName, err := s.agentClientOf(user)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	info, err := agentClient.SSHInfo(ctx, &common.SSHInfoRequest{InstanceName: instanceName})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	signer, err := goSsh.ParsePrivateKey([]byte(info.PrivateKey))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(ctx, metadata.Pairs("instanceName", instanceName)))
+	stream, err := agentClient.Forward(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+
+	local, remote := net.Pipe()
+	go func() {
+		defer cancel()
+		defer remote.Close()
+		if err := common.PipeForward(stream, remote, remote); err != nil && ctx.Err() == nil {
+			log.Printf("ssh forward of %s ended: %v", user, err)
+		}
+	}()
+
+	config := &goSsh.ClientConfig{
+		User:            info.Username,
+		Auth:            []goSsh.AuthMethod{goSsh.PublicKeys(signer)},
+		HostKeyCallback: goSsh.InsecureIgnoreHostKey(), // nolint:gosec
+		Timeout:         handshakeTimeout,
+	}
+	conn, chans, reqs, err := goSsh.NewClientConn(local, instanceName, config)
+	if err != nil {
+		_ = local.Close()
+		return nil, nil, nil, err
+	}
+	return conn, chans, reqs, nil
+}
+
+func (s *server) track(conn *goSsh.ServerConn, add bool) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	if add {
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+}
+
+func (s *server) handle(netConn net.Conn) {
+	defer s.wg.Done()
+
+	_ = netConn.SetDeadline(time.Now().Add(handshakeTimeout))
+	conn, chans, reqs, err := goSsh.NewServerConn(netConn, s.config)
+	if err != nil {
+		log.Printf("ssh handshake with %s failed: %v", netConn.RemoteAddr(), err)
+		_ = netConn.Close()
+		return
+	}
+	_ = netConn.SetDeadline(time.Time{})
+	defer conn.Close()
+
+	s.track(conn, true)
+	defer s.track(conn, false)
+
+	identity := conn.Permissions.Extensions[identityKey]
+	// remote forwarding has no place to listen on, so global requests are refused
+	go goSsh.DiscardRequests(reqs)
+
+	upstream, upstreamChans, upstreamReqs, err := s.dial(context.Background(), conn.User())
+	if err != nil {
+		log.Printf("ssh %s as %s failed: %v", conn.User(), identity, err)
+		for newChannel := range chans {
+			_ = newChannel.Reject(goSsh.ConnectionFailed, err.Error())
+		}
+		return
+	}
+	defer upstream.Close()
+
+	log.Printf("ssh connected: %s as %s from %s", conn.User(), identity, conn.RemoteAddr())
+	defer log.Printf("ssh disconnected: %s as %s", conn.User(), identity)
+
+	go goSsh.DiscardRequests(upstreamReqs)
+	go func() {
+		for newChannel := range upstreamChans {
+			_ = newChannel.Reject(goSsh.Prohibited, "channels from instances are not forwarded")
+		}
+	}()
+	go func() {
+		// the instance going away ends the client connection too
+		_ = upstream.Wait()
+		_ = conn.Close()
+	}()
+
+	for newChannel := range chans {
+		go proxyChannel(newChannel, upstream)
+	}
+}
+
+// Serve accepts ssh connections until the server stops.
+func (s *server) Serve() error {
+	for {
+		netConn, err := s.lis.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		s.wg.Add(1)
+		go s.handle(netConn)
+	}
+}
+
+// GracefulStop stops accepting and waits for open connections until timeout,
+// closing the rest.
+func (s *server) GracefulStop(timeout time.Duration) error {
+	err := s.lis.Close()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.connsMu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.connsMu.Unlock()
+		<-done
+	}
+	return err
+}
+
+// NewServer serves ssh at addr, authenticating users by their keys and proxying
+// their channels to the instance named by the ssh user.
+func NewServer(addr string, hostKeyPath string, keys AuthorizedKeys, clusterServer cluster.Server) (Server, error) {
+	hostKey, err := loadHostKey(hostKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh host key: %w", err)
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &server{
+		clusterServer: clusterServer,
+		lis:           lis,
+		keys:          keys,
+		conns:         make(map[*goSsh.ServerConn]struct{}),
+	}
+	s.config = &goSsh.ServerConfig{
+		PublicKeyCallback: s.authenticate,
+		ServerVersion:     "SSH-2.0-multiverse",
+	}
+	s.config.AddHostKey(hostKey)
+	return s, nil
+}
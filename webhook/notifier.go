@@ -0,0 +1,322 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/erayarslan/multiverse/common"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	recentDeliveries = 100
+	queueSize        = 256
+	initialBackoff   = time.Second
+	maxBackoff       = time.Minute
+)
+
+type notifier struct {
+	httpClient     *http.Client
+	queues         map[*Sink]*sinkQueue
+	deadLetterPath string
+	sinks          []*Sink
+	deliveries     []*common.WebhookDelivery
+	initialBackoff time.Duration
+	deliveriesMu   sync.RWMutex
+	sinksMu        sync.RWMutex
+	deadLetterMu   sync.Mutex
+}
+
+type Notifier interface {
+	Run(events <-chan *common.Event)
+	Dropped(event *common.Event)
+	SetSinks(sinks []*Sink)
+	Deliveries() []*common.WebhookDelivery
+}
+
+func (n *notifier) Deliveries() []*common.WebhookDelivery {
+	n.deliveriesMu.RLock()
+	defer n.deliveriesMu.RUnlock()
+	deliveries := make([]*common.WebhookDelivery, len(n.deliveries))
+	copy(deliveries, n.deliveries)
+	return deliveries
+}
+
+func (n *notifier) record(delivery *common.WebhookDelivery) {
+	n.deliveriesMu.Lock()
+	defer n.deliveriesMu.Unlock()
+	n.deliveries = append(n.deliveries, delivery)
+	if len(n.deliveries) > recentDeliveries {
+		n.deliveries = n.deliveries[len(n.deliveries)-recentDeliveries:]
+	}
+}
+
+func (n *notifier) deadLetter(delivery *common.WebhookDelivery) {
+	delivery.DeadLettered = true
+	log.Printf("webhook %s dead lettered %s event: %s", delivery.Sink, delivery.Event.Type, delivery.Error)
+
+	if n.deadLetterPath == "" {
+		return
+	}
+
+	line, err := protojson.Marshal(delivery)
+	if err != nil {
+		log.Printf("failed to marshal dead letter: %v", err)
+		return
+	}
+
+	n.deadLetterMu.Lock()
+	defer n.deadLetterMu.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(n.deadLetterPath), 0o700); err != nil {
+		log.Printf("failed to create dead letter dir: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(n.deadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Printf("failed to open dead letter log: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		log.Printf("failed to write dead letter log: %v", err)
+	}
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *notifier) post(sink *Sink, id string, event *common.Event, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sink.Timeout.Duration)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Multiverse-Event", event.Type.String())
+	req.Header.Set("X-Multiverse-Delivery", id)
+	if sink.Secret != "" {
+		req.Header.Set("X-Multiverse-Signature", sign(sink.Secret, body))
+	}
+
+	res, err := n.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// pending is an event on its way to a sink, attempted again with backoff
+// until it is delivered or out of retries.
+type pending struct {
+	event   *common.Event
+	id      string
+	body    []byte
+	backoff time.Duration
+	attempt int
+}
+
+func (n *notifier) deliver(q *sinkQueue, event *common.Event) {
+	body, err := protojson.Marshal(event)
+	if err != nil {
+		log.Printf("failed to marshal event: %v", err)
+		return
+	}
+
+	n.attempt(q, &pending{
+		event:   event,
+		id:      uuid.Must(uuid.NewRandom()).String(),
+		body:    body,
+		backoff: n.initialBackoff,
+		attempt: 1,
+	})
+}
+
+// attempt posts d once, scheduling the next attempt instead of waiting for
+// it so later events of the sink are not held up.
+func (n *notifier) attempt(q *sinkQueue, d *pending) {
+	sink := q.sink
+	statusCode, err := n.post(sink, d.id, d.event, d.body)
+
+	delivery := &common.WebhookDelivery{
+		Id:         d.id,
+		Sink:       sink.Name,
+		Url:        sink.URL,
+		Event:      d.event,
+		Attempt:    int32(d.attempt),
+		StatusCode: int32(statusCode),
+		Time:       timestamppb.Now(),
+		Delivered:  err == nil,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	retry := err != nil && d.attempt <= sink.MaxRetries
+	if retry && !q.reserveRetry(d) {
+		retry = false
+		delivery.Error += ", too many deliveries retrying"
+	}
+	if err != nil && !retry {
+		n.deadLetter(delivery)
+	}
+	n.record(delivery)
+
+	if !retry {
+		return
+	}
+	next := &pending{
+		event:   d.event,
+		id:      d.id,
+		body:    d.body,
+		backoff: min(d.backoff*2, maxBackoff),
+		attempt: d.attempt + 1,
+	}
+	time.AfterFunc(d.backoff, func() {
+		defer q.releaseRetry()
+		n.attempt(q, next)
+	})
+}
+
+// sinkQueue holds the events waiting for a sink and bounds its deliveries
+// waiting to be retried.
+type sinkQueue struct {
+	sink     *Sink
+	events   chan *common.Event
+	retrying chan struct{}
+}
+
+// reserveRetry counts d as retrying unless it already is or too many are.
+func (q *sinkQueue) reserveRetry(d *pending) bool {
+	if d.attempt > 1 {
+		return true
+	}
+	select {
+	case q.retrying <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *sinkQueue) releaseRetry() {
+	<-q.retrying
+}
+
+func (n *notifier) work(q *sinkQueue) {
+	for event := range q.events {
+		n.deliver(q, event)
+	}
+}
+
+// SetSinks replaces the sinks, events already queued for removed sinks are
+// still delivered.
+func (n *notifier) SetSinks(sinks []*Sink) {
+	n.sinksMu.Lock()
+	defer n.sinksMu.Unlock()
+
+	for _, q := range n.queues {
+		close(q.events)
+	}
+
+	n.sinks = sinks
+	n.queues = make(map[*Sink]*sinkQueue, len(sinks))
+	for _, sink := range sinks {
+		q := &sinkQueue{
+			sink:     sink,
+			events:   make(chan *common.Event, queueSize),
+			retrying: make(chan struct{}, queueSize),
+		}
+		n.queues[sink] = q
+		go n.work(q)
+	}
+}
+
+// drop dead letters an event for sink which is never attempted.
+func (n *notifier) drop(sink *Sink, event *common.Event, reason string) {
+	delivery := &common.WebhookDelivery{
+		Id:    uuid.Must(uuid.NewRandom()).String(),
+		Sink:  sink.Name,
+		Url:   sink.URL,
+		Event: event,
+		Time:  timestamppb.Now(),
+		Error: reason,
+	}
+	n.deadLetter(delivery)
+	n.record(delivery)
+}
+
+func (n *notifier) dispatch(event *common.Event) {
+	n.sinksMu.RLock()
+	defer n.sinksMu.RUnlock()
+
+	for _, sink := range n.sinks {
+		if !sink.matches(event) {
+			continue
+		}
+		select {
+		case n.queues[sink].events <- event:
+		default:
+			n.drop(sink, event, "delivery queue is full")
+		}
+	}
+}
+
+// Dropped dead letters an event the notifier fell too far behind to receive.
+func (n *notifier) Dropped(event *common.Event) {
+	n.sinksMu.RLock()
+	defer n.sinksMu.RUnlock()
+
+	for _, sink := range n.sinks {
+		if sink.matches(event) {
+			n.drop(sink, event, "event dropped for slow subscriber")
+		}
+	}
+}
+
+func (n *notifier) Run(events <-chan *common.Event) {
+	for event := range events {
+		n.dispatch(event)
+	}
+
+	n.SetSinks(nil)
+}
+
+func NewNotifier(sinks []*Sink, deadLetterPath string) Notifier {
+	n := &notifier{
+		httpClient:     &http.Client{},
+		deadLetterPath: deadLetterPath,
+		initialBackoff: initialBackoff,
+		deliveries:     make([]*common.WebhookDelivery, 0, recentDeliveries),
+	}
+	n.SetSinks(sinks)
+	return n
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/erayarslan/multiverse/common"
+)
+
+const deliveryTimeout = 5 * time.Second
+
+// fakeSink answers the first failures requests with an error status,
+// recording the requests it receives.
+type fakeSink struct {
+	*httptest.Server
+	requests []*http.Request
+	bodies   [][]byte
+	calls    atomic.Int32
+	failures int32
+	mu       sync.Mutex
+}
+
+func newFakeSink(t *testing.T, failures int32) *fakeSink {
+	t.Helper()
+	s := &fakeSink{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+		if s.calls.Add(1) <= s.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestNotifier(t *testing.T, sinks ...*Sink) *notifier {
+	t.Helper()
+	n := &notifier{
+		httpClient:     &http.Client{},
+		deadLetterPath: filepath.Join(t.TempDir(), "dead-letter.jsonl"),
+		initialBackoff: time.Millisecond,
+	}
+	for _, sink := range sinks {
+		if err := sink.validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.SetSinks(sinks)
+	t.Cleanup(func() { n.SetSinks(nil) })
+	return n
+}
+
+// waitDeliveries waits until n recorded count deliveries.
+func waitDeliveries(t *testing.T, n *notifier, count int) []*common.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(deliveryTimeout)
+	for time.Now().Before(deadline) {
+		if deliveries := n.Deliveries(); len(deliveries) >= count {
+			return deliveries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("got %d deliveries within %v, want %d", len(n.Deliveries()), deliveryTimeout, count)
+	return nil
+}
+
+func deadLetters(t *testing.T, n *notifier) []string {
+	t.Helper()
+	b, err := os.ReadFile(n.deadLetterPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func TestSignedHeaders(t *testing.T) {
+	sink := newFakeSink(t, 0)
+	n := newTestNotifier(t, &Sink{URL: sink.URL, Secret: "s3cret"})
+
+	n.dispatch(&common.Event{Type: common.EventType_NODE_JOINED})
+	deliveries := waitDeliveries(t, n, 1)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	req, body := sink.requests[0], sink.bodies[0]
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get("X-Multiverse-Signature") != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get("X-Multiverse-Signature"), want)
+	}
+	if got := req.Header.Get("X-Multiverse-Event"); got != "NODE_JOINED" {
+		t.Errorf("event header = %q, want %q", got, "NODE_JOINED")
+	}
+	if got := req.Header.Get("X-Multiverse-Delivery"); got != deliveries[0].Id {
+		t.Errorf("delivery header = %q, want %q", got, deliveries[0].Id)
+	}
+}
+
+func TestUnsignedWithoutSecret(t *testing.T) {
+	sink := newFakeSink(t, 0)
+	n := newTestNotifier(t, &Sink{URL: sink.URL})
+
+	n.dispatch(&common.Event{Type: common.EventType_NODE_JOINED})
+	waitDeliveries(t, n, 1)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if got := sink.requests[0].Header.Get("X-Multiverse-Signature"); got != "" {
+		t.Errorf("signature = %q, want none", got)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxRetries     int
+		wantAttempts   int
+		failures       int32
+		wantDelivered  bool
+		wantDeadLetter bool
+	}{
+		{name: "delivered", maxRetries: 3, failures: 0, wantAttempts: 1, wantDelivered: true},
+		{name: "delivered after retries", maxRetries: 3, failures: 2, wantAttempts: 3, wantDelivered: true},
+		{name: "retries disabled", maxRetries: 0, failures: 1, wantAttempts: 1, wantDeadLetter: true},
+		{name: "out of retries", maxRetries: 2, failures: 10, wantAttempts: 3, wantDeadLetter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := newFakeSink(t, tt.failures)
+			n := newTestNotifier(t, &Sink{Name: "sink", URL: sink.URL, MaxRetries: tt.maxRetries})
+
+			n.dispatch(&common.Event{Type: common.EventType_INSTANCE_APPEARED})
+			waitDeliveries(t, n, tt.wantAttempts)
+			// give an unexpected extra attempt the time to show up
+			time.Sleep(50 * time.Millisecond)
+			deliveries := n.Deliveries()
+
+			if len(deliveries) != tt.wantAttempts || int(sink.calls.Load()) != tt.wantAttempts {
+				t.Fatalf("got %d deliveries and %d requests, want %d", len(deliveries), sink.calls.Load(), tt.wantAttempts)
+			}
+			for i, d := range deliveries {
+				if int(d.Attempt) != i+1 || d.Id != deliveries[0].Id {
+					t.Errorf("delivery %d is attempt %d of %s, want attempt %d of %s", i, d.Attempt, d.Id, i+1, deliveries[0].Id)
+				}
+			}
+			last := deliveries[len(deliveries)-1]
+			if last.Delivered != tt.wantDelivered || last.DeadLettered != tt.wantDeadLetter {
+				t.Errorf("last delivery delivered %v dead lettered %v, want %v and %v",
+					last.Delivered, last.DeadLettered, tt.wantDelivered, tt.wantDeadLetter)
+			}
+
+			lines := deadLetters(t, n)
+			if tt.wantDeadLetter != (len(lines) == 1) {
+				t.Fatalf("dead letter log = %q, want dead lettered %v", lines, tt.wantDeadLetter)
+			}
+			if tt.wantDeadLetter && !strings.Contains(lines[0], last.Id) {
+				t.Errorf("dead letter log = %q, want delivery %s", lines[0], last.Id)
+			}
+		})
+	}
+}
+
+func TestRetryDoesNotBlockQueue(t *testing.T) {
+	sink := newFakeSink(t, 1)
+	n := newTestNotifier(t, &Sink{URL: sink.URL, MaxRetries: 1})
+	n.initialBackoff = time.Hour
+
+	n.dispatch(&common.Event{Type: common.EventType_NODE_JOINED})
+	n.dispatch(&common.Event{Type: common.EventType_NODE_LEFT})
+	deliveries := waitDeliveries(t, n, 2)
+
+	if deliveries[0].Delivered || !deliveries[1].Delivered || deliveries[1].Event.Type != common.EventType_NODE_LEFT {
+		t.Errorf("deliveries = %v, want the second event delivered while the first waits", deliveries)
+	}
+}
+
+func TestLoadSinksMaxRetries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sinks.json")
+	err := os.WriteFile(path, []byte(`[
+		{"name": "default", "url": "http://localhost/a"},
+		{"name": "disabled", "url": "http://localhost/b", "maxRetries": 0},
+		{"name": "set", "url": "http://localhost/c", "maxRetries": 2}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sinks, err := LoadSinks(path)
+	if err != nil {
+		t.Fatalf("LoadSinks() error = %v", err)
+	}
+	want := map[string]int{"default": defaultMaxRetries, "disabled": 0, "set": 2}
+	for _, sink := range sinks {
+		if sink.MaxRetries != want[sink.Name] {
+			t.Errorf("sink %s max retries = %d, want %d", sink.Name, sink.MaxRetries, want[sink.Name])
+		}
+	}
+}
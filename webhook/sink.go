@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/erayarslan/multiverse/common"
+)
+
+const (
+	defaultMaxRetries = 5
+	defaultTimeout    = 10 * time.Second
+)
+
+type Sink struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	Types      []string `json:"types"`
+	MaxRetries int      `json:"maxRetries"`
+	Timeout    Duration `json:"timeout"`
+}
+
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (s *Sink) validate() error {
+	if s.URL == "" {
+		return fmt.Errorf("webhook sink %s has no url", s.Name)
+	}
+	for _, t := range s.Types {
+		if _, ok := common.EventType_value[strings.ToUpper(t)]; !ok {
+			return fmt.Errorf("webhook sink %s has unknown event type: %s", s.Name, t)
+		}
+	}
+	if s.Name == "" {
+		s.Name = s.URL
+	}
+	if s.MaxRetries < 0 {
+		s.MaxRetries = defaultMaxRetries
+	}
+	if s.Timeout.Duration <= 0 {
+		s.Timeout.Duration = defaultTimeout
+	}
+	return nil
+}
+
+func (s *Sink) matches(event *common.Event) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if strings.EqualFold(t, event.Type.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+func LoadSinks(path string) ([]*Sink, error) {
+	if path == "" {
+		return []*Sink{}, nil
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]json.RawMessage, 0)
+	if err = json.Unmarshal(bytes, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook sinks: %w", err)
+	}
+
+	sinks := make([]*Sink, 0, len(raw))
+	for _, r := range raw {
+		// maxRetries 0 disables retries, so only a missing one takes the default
+		sink := &Sink{MaxRetries: -1}
+		if err = json.Unmarshal(r, sink); err != nil {
+			return nil, fmt.Errorf("failed to parse webhook sinks: %w", err)
+		}
+		if err = sink.validate(); err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}